@@ -0,0 +1,346 @@
+// Package cdv validates South African bank account numbers locally with check digit verification (CDV) rules,
+// as an alternative to the remote validation in cloud.ValidateCDV.
+//
+// The weightings per branch code range are published by BankservAfrica and change from time to time.
+// DefaultRules returns the table embedded from rules.csv, which is updated with each release of the tables;
+// load another version with ParseRules or LoadRules.
+package cdv
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"github.com/Direct-Debit/go-commons/cloud"
+	"github.com/Direct-Debit/go-commons/fileio"
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Keys of the errors returned by Validator.Validate, the same as the fields the remote validation reports on.
+const (
+	FieldNumber      = "number"
+	FieldBranch      = "branch"
+	FieldAccountType = "account_type"
+)
+
+// Account types accepted by Validate. Rules with an empty account type apply to all of them.
+const (
+	AccountCurrent      = "current"
+	AccountSavings      = "savings"
+	AccountTransmission = "transmission"
+	AccountBond         = "bond"
+	AccountSubscription = "subscription"
+)
+
+var accountTypes = map[string]string{
+	"current":      AccountCurrent,
+	"cheque":       AccountCurrent,
+	"checking":     AccountCurrent,
+	"1":            AccountCurrent,
+	"savings":      AccountSavings,
+	"2":            AccountSavings,
+	"transmission": AccountTransmission,
+	"3":            AccountTransmission,
+	"bond":         AccountBond,
+	"4":            AccountBond,
+	"subscription": AccountSubscription,
+	"6":            AccountSubscription,
+}
+
+// AccountLength is the length account numbers are zero-padded to before the weights are applied.
+const AccountLength = 11
+
+// Weighting is one check of an account number: the sum of every digit times its weight, plus the fudge factor,
+// must be divisible by the modulus.
+type Weighting struct {
+	Weights [AccountLength]int
+	Fudge   int
+	Modulus int
+	// DigitSum adds the digits of every product instead of the product, e.g. 8 times 2 adds 1+6,
+	// like the Luhn check.
+	DigitSum bool
+}
+
+func (w Weighting) check(number string) bool {
+	if w.Modulus <= 0 {
+		return false
+	}
+	sum := w.Fudge
+	for i, d := range number {
+		product := int(d-'0') * w.Weights[i]
+		if w.DigitSum {
+			product = digitSum(product)
+		}
+		sum += product
+	}
+	return sum%w.Modulus == 0
+}
+
+func digitSum(n int) int {
+	if n < 0 {
+		n = -n
+	}
+	sum := 0
+	for ; n > 0; n /= 10 {
+		sum += n % 10
+	}
+	return sum
+}
+
+// Methods of adding up the weighted digits, in the last column of the rules.
+const (
+	MethodSum      = "sum"      // The default: the products of the digits and their weights are added
+	MethodDigitSum = "digitsum" // The digits of the products are added, see Weighting.DigitSum
+)
+
+// Rule is the CDV rule for a range of branch codes and an account type.
+// An account number is valid if it passes any of the weightings.
+type Rule struct {
+	FromBranch  int
+	ToBranch    int
+	AccountType string // Empty for all account types
+	Weightings  []Weighting
+	// Exception is the code of a bank specific check that replaces the standard weighting check, see RegisterException.
+	Exception string
+}
+
+func (r Rule) matches(branch int, accountType string) bool {
+	return branch >= r.FromBranch && branch <= r.ToBranch && (r.AccountType == "" || r.AccountType == accountType)
+}
+
+// ExceptionFunc checks an account number, zero-padded to AccountLength, for rules with an exception code.
+type ExceptionFunc func(number string, rule Rule) bool
+
+var (
+	exceptionsLock sync.RWMutex
+	exceptions     = map[string]ExceptionFunc{}
+)
+
+// RegisterException adds the bank specific check for an exception code in the published rules.
+// Rules with an exception code that has not been registered are refused by ParseRules.
+func RegisterException(code string, check ExceptionFunc) {
+	exceptionsLock.Lock()
+	defer exceptionsLock.Unlock()
+	exceptions[code] = check
+}
+
+func lookupException(code string) (ExceptionFunc, bool) {
+	exceptionsLock.RLock()
+	defer exceptionsLock.RUnlock()
+	check, ok := exceptions[code]
+	return check, ok
+}
+
+//go:embed rules.csv
+var defaultRules string
+
+// ErrNoRules is returned by DefaultRules while rules.csv has no rows, so a Validator is not silently created
+// without rules, which would report every branch as unknown or send it to the Fallback.
+var ErrNoRules = errors.New("the embedded CDV rules table has no rows")
+
+// DefaultRules returns the rules embedded in the package, see the comments in rules.csv for their version.
+func DefaultRules() ([]Rule, error) {
+	rules, err := ParseRules(defaultRules)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, ErrNoRules
+	}
+	return rules, nil
+}
+
+// ParseRules reads rules from CSV with the columns from branch, to branch, account type, modulus, fudge factor,
+// weights (separated by spaces), exception code, and optionally the method, MethodSum or MethodDigitSum.
+// Rows with the same branch range and account type are alternative weightings of one rule.
+// A header row starting with a non-numeric branch is skipped.
+func ParseRules(content string) ([]Rule, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read CDV rules")
+	}
+
+	var rules []Rule
+	for i, row := range rows {
+		if i == 0 && !isDigits(row[0]) {
+			continue
+		}
+		rule, err := parseRule(row)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid CDV rule on row %d", i+1)
+		}
+
+		last := len(rules) - 1
+		if last >= 0 && rules[last].FromBranch == rule.FromBranch && rules[last].ToBranch == rule.ToBranch &&
+			rules[last].AccountType == rule.AccountType {
+			rules[last].Weightings = append(rules[last].Weightings, rule.Weightings...)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(row []string) (Rule, error) {
+	var rule Rule
+	var w Weighting
+	var err error
+	if len(row) != 7 && len(row) != 8 {
+		return rule, fmt.Errorf("%d columns instead of 7 or 8", len(row))
+	}
+	if rule.FromBranch, err = strconv.Atoi(row[0]); err != nil {
+		return rule, errors.Wrap(err, "invalid from branch")
+	}
+	if rule.ToBranch, err = strconv.Atoi(row[1]); err != nil {
+		return rule, errors.Wrap(err, "invalid to branch")
+	}
+	if rule.ToBranch < rule.FromBranch {
+		return rule, fmt.Errorf("branch range %d-%d is empty", rule.FromBranch, rule.ToBranch)
+	}
+	if row[2] != "" {
+		accountType, ok := accountTypes[strings.ToLower(row[2])]
+		if !ok {
+			return rule, fmt.Errorf("unknown account type %s", row[2])
+		}
+		rule.AccountType = accountType
+	}
+	if w.Modulus, err = strconv.Atoi(row[3]); err != nil || w.Modulus <= 0 {
+		return rule, fmt.Errorf("invalid modulus %s", row[3])
+	}
+	if w.Fudge, err = strconv.Atoi(row[4]); err != nil {
+		return rule, errors.Wrap(err, "invalid fudge factor")
+	}
+
+	weights := strings.Fields(row[5])
+	if len(weights) != AccountLength {
+		return rule, fmt.Errorf("%d weights instead of %d", len(weights), AccountLength)
+	}
+	for i, s := range weights {
+		if w.Weights[i], err = strconv.Atoi(s); err != nil {
+			return rule, errors.Wrap(err, "invalid weight")
+		}
+	}
+	if len(row) == 8 {
+		switch strings.ToLower(row[7]) {
+		case "", MethodSum:
+		case MethodDigitSum:
+			w.DigitSum = true
+		default:
+			return rule, fmt.Errorf("unknown method %s", row[7])
+		}
+	}
+	rule.Weightings = []Weighting{w}
+
+	rule.Exception = row[6]
+	if _, ok := lookupException(rule.Exception); rule.Exception != "" && !ok {
+		return rule, fmt.Errorf("exception %s is not registered", rule.Exception)
+	}
+	return rule, nil
+}
+
+// LoadRules loads a file in the format read by ParseRules.
+func LoadRules(store fileio.FileStore, path string) ([]Rule, error) {
+	content, err := store.Load(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not load CDV rules from %s", path)
+	}
+	return ParseRules(content)
+}
+
+// Validator checks account numbers against CDV rules. It implements cloud.CdvValidator.
+type Validator struct {
+	rules []Rule
+	// Fallback validates accounts at branches that have no rule, e.g. the remote validation.
+	// If nil, those branches are reported as unknown.
+	Fallback cloud.CdvValidator
+}
+
+var _ cloud.CdvValidator = (*Validator)(nil)
+
+// NewValidator creates a Validator for the rules, optionally falling back to another validator for unknown branches.
+func NewValidator(rules []Rule, fallback cloud.CdvValidator) *Validator {
+	return &Validator{rules: rules, Fallback: fallback}
+}
+
+// Validate checks the account number, branch code and account type, and returns a message for every field that
+// is invalid, keyed by FieldNumber, FieldBranch or FieldAccountType. The map is empty if the account is valid.
+// If more than one rule matches the branch and account type, the account is valid if it passes any of them,
+// so the order of the rules doesn't matter. Errors are only returned by the Fallback.
+func (v *Validator) Validate(number string, branch string, accountType string) (map[string]string, error) {
+	result := make(map[string]string)
+	number = strings.TrimSpace(number)
+	if number == "" || len(number) > AccountLength || !isDigits(number) {
+		result[FieldNumber] = fmt.Sprintf("account number must be 1 to %d digits", AccountLength)
+	}
+	branchCode, err := strconv.Atoi(strings.TrimSpace(branch))
+	if len(strings.TrimSpace(branch)) != 6 || err != nil || branchCode < 0 {
+		result[FieldBranch] = "branch code must be 6 digits"
+	}
+	normalType, ok := accountTypes[strings.ToLower(strings.TrimSpace(accountType))]
+	if !ok {
+		result[FieldAccountType] = fmt.Sprintf("unknown account type %s", accountType)
+	}
+	if len(result) > 0 {
+		return result, nil
+	}
+
+	padded := strings.Repeat("0", AccountLength-len(number)) + number
+	knownBranch, matched := false, false
+	for _, r := range v.rules {
+		if branchCode < r.FromBranch || branchCode > r.ToBranch {
+			continue
+		}
+		knownBranch = true
+		if !r.matches(branchCode, normalType) {
+			continue
+		}
+		matched = true
+		if r.valid(padded) {
+			return result, nil
+		}
+	}
+	if matched {
+		result[FieldNumber] = "account number failed check digit verification"
+		return result, nil
+	}
+
+	if !knownBranch && v.Fallback != nil {
+		return v.Fallback.Validate(number, branch, accountType)
+	}
+	if knownBranch {
+		result[FieldAccountType] = fmt.Sprintf("%s accounts are not allowed at branch %s", normalType, branch)
+	} else {
+		result[FieldBranch] = fmt.Sprintf("unknown branch code %s", branch)
+	}
+	return result, nil
+}
+
+func (r Rule) valid(number string) bool {
+	if r.Exception != "" {
+		if check, ok := lookupException(r.Exception); ok {
+			return check(number, r)
+		}
+		return false
+	}
+	for _, w := range r.Weightings {
+		if w.check(number) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,134 @@
+package cdv
+
+import (
+	"errors"
+	"github.com/Direct-Debit/go-commons/fileio"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testRules = `from,to,type,modulus,fudge,weights,exception
+100000,199999,,10,0,1 1 1 1 1 1 1 1 1 1 1,
+100000,199999,,7,0,0 0 0 0 0 0 0 0 0 0 1,
+200000,200099,savings,11,0,0 0 0 0 0 0 0 0 0 0 0,
+300000,300000,,11,0,1 2 3 4 5 6 7 8 9 10 11,T
+`
+
+type stubValidator map[string]string
+
+func (s stubValidator) Validate(_ string, _ string, _ string) (map[string]string, error) {
+	return s, nil
+}
+
+func init() {
+	RegisterException("T", func(number string, _ Rule) bool { return strings.HasSuffix(number, "9") })
+}
+
+func TestValidator(t *testing.T) {
+	rules, err := ParseRules(testRules)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 3)
+	assert.Len(t, rules[0].Weightings, 2)
+
+	v := NewValidator(rules, nil)
+	cases := []struct {
+		number, branch, accountType string
+		errors                      []string
+	}{
+		{"19", "150000", "current", nil},
+		{"17", "150000", "cheque", nil},
+		{"18", "150000", "savings", []string{FieldNumber}},
+		{"12345", "200050", "savings", nil},
+		{"12345", "200050", "current", []string{FieldAccountType}},
+		{"12349", "300000", "1", nil},
+		{"12348", "300000", "1", []string{FieldNumber}},
+		{"19", "900000", "savings", []string{FieldBranch}},
+		{"12a", "15000", "loan", []string{FieldNumber, FieldBranch, FieldAccountType}},
+		{"123456789012", "150000", "savings", []string{FieldNumber}},
+	}
+	for _, c := range cases {
+		result, err := v.Validate(c.number, c.branch, c.accountType)
+		assert.NoError(t, err)
+		var fields []string
+		for _, f := range []string{FieldNumber, FieldBranch, FieldAccountType} {
+			if _, ok := result[f]; ok {
+				fields = append(fields, f)
+			}
+		}
+		assert.Equal(t, c.errors, fields, "%s at %s", c.number, c.branch)
+	}
+
+	fallback := stubValidator{"number": "remote"}
+	v = NewValidator(rules, fallback)
+	result, err := v.Validate("19", "900000", "savings")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string(fallback), result)
+	result, err = v.Validate("19", "150000", "savings")
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+}
+
+func TestDigitSumWeighting(t *testing.T) {
+	rules, err := ParseRules("400000,400999,,10,0,0 0 0 0 0 0 0 2 1 2 1,,digitsum\n")
+	assert.NoError(t, err)
+	assert.True(t, rules[0].Weightings[0].DigitSum)
+
+	v := NewValidator(rules, nil)
+	result, _ := v.Validate("8763", "400100", "current")
+	assert.Empty(t, result, "8*2=16 adds 1+6 and 6*2=12 adds 1+2, so the sum is 7+7+3+3=20")
+	result, _ = v.Validate("8764", "400100", "current")
+	assert.Contains(t, result, FieldNumber)
+}
+
+func TestValidateIgnoresRuleOrder(t *testing.T) {
+	rules, err := ParseRules(`500000,599999,,10,0,0 0 0 0 0 0 0 0 0 0 1,
+550000,550999,current,10,0,1 1 1 1 1 1 1 1 1 1 1,
+`)
+	assert.NoError(t, err)
+	reversed := []Rule{rules[1], rules[0]}
+
+	for _, number := range []string{"10", "19", "55"} {
+		first, _ := NewValidator(rules, nil).Validate(number, "550500", "current")
+		second, _ := NewValidator(reversed, nil).Validate(number, "550500", "current")
+		assert.Equal(t, first, second, number)
+	}
+	result, _ := NewValidator(reversed, nil).Validate("10", "550500", "current")
+	assert.Empty(t, result, "the account passes the broader rule")
+}
+
+func TestDefaultRules(t *testing.T) {
+	rules, err := DefaultRules()
+	if errors.Is(err, ErrNoRules) {
+		t.Skip("rules.csv has no rows yet")
+	}
+	assert.NoError(t, err)
+	assert.NotEmpty(t, rules)
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	invalid := []string{
+		"100000,99999,,10,0,1 1 1 1 1 1 1 1 1 1 1,",
+		"100000,199999,loan,10,0,1 1 1 1 1 1 1 1 1 1 1,",
+		"100000,199999,,0,0,1 1 1 1 1 1 1 1 1 1 1,",
+		"100000,199999,,10,0,1 1 1,",
+		"100000,199999,,10,0,1 1 1 1 1 1 1 1 1 1 1,UNKNOWN",
+		"100000,199999,,10,0",
+		"100000,199999,,10,0,1 1 1 1 1 1 1 1 1 1 1,,product",
+	}
+	for _, rules := range invalid {
+		_, err := ParseRules(rules)
+		assert.Error(t, err, rules)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	store := fileio.SimpleFileStore{BasePath: t.TempDir()}
+	assert.NoError(t, store.Save("cdv.csv", testRules))
+	rules, err := LoadRules(store, "cdv.csv")
+	assert.NoError(t, err)
+	assert.Len(t, rules, 3)
+
+	_, err = LoadRules(store, "missing.csv")
+	assert.Error(t, err)
+}
@@ -2,32 +2,109 @@ package dynamo
 
 import (
 	"fmt"
+	"github.com/Direct-Debit/go-commons/errlib"
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/pkg/errors"
+	"math"
+	"sync"
+	"time"
 )
 
 const tableName = "config"
 const keyColumnName = "key"
+const valueColumnName = "value"
 
-type Config struct{}
+// DefaultConfigTTL is how long a Config made with NewConfig serves values before it loads the table again.
+const DefaultConfigTTL = time.Minute
 
-func (c Config) Query(key string) (interface{}, error) {
-	sess := session.Must(session.NewSessionWithOptions(session.Options{
-		SharedConfigState: session.SharedConfigEnable,
-	}))
-	connection := dynamodb.New(sess)
+// Config is a config.Provider for a DynamoDB table with a key column and a "value" column.
+//
+// Create it with NewConfig, which loads the whole table at once, serves values from memory,
+// and loads the table again when the values are older than TTL, or when Reload is called.
+// It returns numbers without a fraction as int64, like the TOML provider does, so config.GetInt64 works.
+//
+// A Config that is not made with NewConfig, like Config{}, has no cache: it ignores TTL, reads the table
+// every time a value is asked for, and returns numbers as float64.
+type Config struct {
+	Table     string
+	KeyColumn string
+	Client    dynamodbiface.DynamoDBAPI
+	// TTL is how long loaded values are served before the table is loaded again. Zero is DefaultConfigTTL.
+	// It is only used by a Config made with NewConfig.
+	TTL time.Duration
+
+	cache *configCache
+}
+
+type configCache struct {
+	lock     sync.RWMutex
+	values   map[string]interface{}
+	loadedAt time.Time
+	now      func() time.Time
+
+	// refresh lets one lookup at a time load expired values, and refreshes counts those loads,
+	// so lookups that waited for another one use its result instead of scanning the table again.
+	refresh   sync.Mutex
+	refreshes int
+	lastErr   error
+}
+
+// NewConfig creates a Config for table, with the key in keyColumn, and loads all its values.
+// If client is nil, the shared connection from Connect is used.
+func NewConfig(table, keyColumn string, client dynamodbiface.DynamoDBAPI) (Config, error) {
+	if client == nil {
+		client = Connect()
+	}
+	c := Config{
+		Table:     table,
+		KeyColumn: keyColumn,
+		Client:    client,
+		cache:     &configCache{now: time.Now},
+	}
+	return c, c.Reload()
+}
+
+func (c Config) client() dynamodbiface.DynamoDBAPI {
+	if c.Client != nil {
+		return c.Client
+	}
+	return Connect()
+}
+
+func (c Config) table() string {
+	if c.Table != "" {
+		return c.Table
+	}
+	return tableName
+}
+
+func (c Config) keyColumn() string {
+	if c.KeyColumn != "" {
+		return c.KeyColumn
+	}
+	return keyColumnName
+}
+
+func (c Config) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return DefaultConfigTTL
+}
 
-	dbKey, err := dynamodbattribute.MarshalMap(map[string]interface{}{keyColumnName: key})
+// Query reads one value from the table, without the cache. It returns nil if the key is not in the table.
+func (c Config) Query(key string) (interface{}, error) {
+	dbKey, err := dynamodbattribute.MarshalMap(map[string]interface{}{c.keyColumn(): key})
 	if err != nil {
 		return nil, errors.Wrap(err, "could not marshal key for dynamo config table")
 	}
 
-	item, err := connection.GetItem(&dynamodb.GetItemInput{
+	item, err := c.client().GetItem(&dynamodb.GetItemInput{
 		Key:       dbKey,
-		TableName: aws.String(tableName),
+		TableName: aws.String(c.table()),
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "could query dynamo config table")
@@ -38,16 +115,133 @@ func (c Config) Query(key string) (interface{}, error) {
 	}
 
 	var value interface{}
-	err = dynamodbattribute.Unmarshal(item.Item["value"], &value)
+	err = dynamodbattribute.Unmarshal(item.Item[valueColumnName], &value)
 	return value, errors.Wrap(err, "Could not unmarshal dynamo config value")
 }
 
+// Reload loads all the values in the table again. It does nothing for Config{}, which doesn't cache values.
 func (c Config) Reload() error {
+	if c.cache == nil {
+		return nil
+	}
+
+	values := map[string]interface{}{}
+	var itemErr error
+	err := c.client().ScanPages(&dynamodb.ScanInput{TableName: aws.String(c.table())},
+		func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			for _, item := range page.Items {
+				var key string
+				if itemErr = dynamodbattribute.Unmarshal(item[c.keyColumn()], &key); itemErr != nil {
+					return false
+				}
+				var value interface{}
+				if itemErr = dynamodbattribute.Unmarshal(item[valueColumnName], &value); itemErr != nil {
+					itemErr = errors.Wrapf(itemErr, "could not unmarshal dynamo config value of %s", key)
+					return false
+				}
+				values[key] = normalizeNumbers(value)
+			}
+			return true
+		})
+	if err == nil {
+		err = itemErr
+	}
+	if err != nil {
+		return errors.Wrapf(err, "could not load dynamo config table %s", c.table())
+	}
+
+	c.cache.lock.Lock()
+	defer c.cache.lock.Unlock()
+	c.cache.values = values
+	c.cache.loadedAt = c.cache.now()
 	return nil
 }
 
+// lookup returns the value of key from the cache, loading the table again first if the values are too old.
+// If loading fails, the old values are served, so a DynamoDB outage doesn't take config away.
+func (c Config) lookup(key string) (interface{}, error) {
+	if c.cache == nil {
+		return c.Query(key)
+	}
+
+	c.cache.lock.RLock()
+	expired := c.cache.now().Sub(c.cache.loadedAt) >= c.ttl()
+	loaded := c.cache.values != nil
+	refreshes := c.cache.refreshes
+	c.cache.lock.RUnlock()
+	if expired {
+		err := c.refresh(refreshes)
+		if !loaded {
+			return nil, err
+		}
+		errlib.WarnError(err, "Serving old dynamo config values")
+	}
+
+	c.cache.lock.RLock()
+	defer c.cache.lock.RUnlock()
+	return c.cache.values[key], nil
+}
+
+// refresh loads the table again, unless another lookup did so since refreshes was read,
+// in which case it returns the result of that load.
+func (c Config) refresh(refreshes int) error {
+	c.cache.refresh.Lock()
+	defer c.cache.refresh.Unlock()
+
+	c.cache.lock.RLock()
+	done, err := c.cache.refreshes != refreshes, c.cache.lastErr
+	c.cache.lock.RUnlock()
+	if done {
+		return err
+	}
+
+	err = c.Reload()
+	c.cache.lock.Lock()
+	defer c.cache.lock.Unlock()
+	c.cache.refreshes++
+	c.cache.lastErr = err
+	return err
+}
+
+// Keys returns the keys that were loaded from the table. Config{} doesn't load the table, so it returns nil.
+func (c Config) Keys() []string {
+	if c.cache == nil {
+		return nil
+	}
+	c.cache.lock.RLock()
+	defer c.cache.lock.RUnlock()
+	keys := make([]string, 0, len(c.cache.values))
+	for k := range c.cache.values {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// normalizeNumbers converts the float64 values dynamodbattribute returns for numbers to int64 if they are whole.
+func normalizeNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+		return v
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalizeNumbers(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
 func (c Config) GetDef(key string, def interface{}) (interface{}, error) {
-	v, err := c.Query(key)
+	v, err := c.lookup(key)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +252,7 @@ func (c Config) GetDef(key string, def interface{}) (interface{}, error) {
 }
 
 func (c Config) Get(key string) (interface{}, error) {
-	v, err := c.Query(key)
+	v, err := c.lookup(key)
 	if err != nil {
 		return nil, err
 	}
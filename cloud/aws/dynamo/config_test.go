@@ -0,0 +1,153 @@
+package dynamo
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConfigTable serves a config table from memory, two items per page.
+type fakeConfigTable struct {
+	dynamodbiface.DynamoDBAPI
+	values map[string]interface{}
+	scans  int
+	err    error
+	// release, if set, blocks scans until it is closed
+	release chan struct{}
+}
+
+func (f *fakeConfigTable) ScanPages(in *dynamodb.ScanInput, fn func(*dynamodb.ScanOutput, bool) bool) error {
+	f.scans++
+	if f.release != nil {
+		<-f.release
+	}
+	if f.err != nil {
+		return f.err
+	}
+	var items []map[string]*dynamodb.AttributeValue
+	for k, v := range f.values {
+		item, err := dynamodbattribute.MarshalMap(map[string]interface{}{"name": k, "value": v})
+		if err != nil {
+			return err
+		}
+		items = append(items, item)
+	}
+	for i := 0; i < len(items); i += 2 {
+		end := i + 2
+		if end > len(items) {
+			end = len(items)
+		}
+		if !fn(&dynamodb.ScanOutput{Items: items[i:end]}, end == len(items)) {
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeConfigTable) GetItem(in *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	var key string
+	_ = dynamodbattribute.Unmarshal(in.Key["name"], &key)
+	v, ok := f.values[key]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	item, err := dynamodbattribute.MarshalMap(map[string]interface{}{"name": key, "value": v})
+	return &dynamodb.GetItemOutput{Item: item}, err
+}
+
+func TestConfigCache(t *testing.T) {
+	table := &fakeConfigTable{values: map[string]interface{}{
+		"log_level": "INFO",
+		"rate":      2.5,
+		"limit":     100,
+		"banks":     []interface{}{"absa", "fnb"},
+		"retries":   3,
+	}}
+	c, err := NewConfig("settings", "name", table)
+	assert.NoError(t, err)
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.cache.now = func() time.Time { return now }
+	c.cache.loadedAt = now
+	assert.Equal(t, 1, table.scans)
+
+	v, err := c.Get("limit")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), v)
+	v, _ = c.Get("rate")
+	assert.Equal(t, 2.5, v)
+	v, _ = c.Get("banks")
+	assert.Equal(t, []interface{}{"absa", "fnb"}, v)
+	_, err = c.Get("missing")
+	assert.Error(t, err)
+	v, err = c.GetDef("missing", "def")
+	assert.NoError(t, err)
+	assert.Equal(t, "def", v)
+	assert.Equal(t, 1, table.scans, "reads are served from the cache")
+
+	table.values["limit"] = 200
+	assert.NoError(t, c.Reload())
+	v, _ = c.Get("limit")
+	assert.Equal(t, int64(200), v)
+
+	table.values["limit"] = 300
+	now = now.Add(DefaultConfigTTL)
+	v, _ = c.Get("limit")
+	assert.Equal(t, int64(300), v, "expired values are loaded again")
+	assert.Equal(t, 3, table.scans)
+
+	table.err = errors.New("throttled")
+	now = now.Add(DefaultConfigTTL)
+	v, err = c.Get("limit")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), v, "old values are served when loading fails")
+	assert.Error(t, c.Reload())
+}
+
+func TestConfigRefreshesOnce(t *testing.T) {
+	table := &fakeConfigTable{values: map[string]interface{}{"limit": 100}}
+	c, err := NewConfig("settings", "name", table)
+	assert.NoError(t, err)
+	expired := c.cache.loadedAt.Add(DefaultConfigTTL)
+	c.cache.now = func() time.Time { return expired }
+
+	table.values["limit"] = 200
+	table.release = make(chan struct{})
+	var wg sync.WaitGroup
+	values := make([]interface{}, 10)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], _ = c.Get("limit")
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(table.release)
+	wg.Wait()
+
+	assert.Equal(t, 2, table.scans, "concurrent lookups of expired values load the table once")
+	for _, v := range values {
+		assert.Equal(t, int64(200), v)
+	}
+}
+
+func TestNewConfigFails(t *testing.T) {
+	_, err := NewConfig("settings", "name", &fakeConfigTable{err: errors.New("no table")})
+	assert.Error(t, err)
+}
+
+func TestConfigWithoutCache(t *testing.T) {
+	table := &fakeConfigTable{values: map[string]interface{}{"limit": 100}}
+	c := Config{KeyColumn: "name", Client: table}
+
+	v, err := c.Get("limit")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(100), v)
+	assert.NoError(t, c.Reload())
+	assert.Equal(t, 0, table.scans)
+}
@@ -20,11 +20,16 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var connection *dynamodb.DynamoDB
+var (
+	connection     *dynamodb.DynamoDB
+	connectionLock sync.Mutex
+)
 
 type Item map[string]*dynamodb.AttributeValue
 
 func Connect() *dynamodb.DynamoDB {
+	connectionLock.Lock()
+	defer connectionLock.Unlock()
 	if connection != (*dynamodb.DynamoDB)(nil) {
 		return connection
 	}
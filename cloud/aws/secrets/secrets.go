@@ -0,0 +1,153 @@
+// Package secrets resolves references to secrets in AWS SSM Parameter Store and Secrets Manager,
+// so config files can hold "ssm:/prod/sftp/password" instead of the password. See config.NewSecrets.
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/aws/aws-sdk-go/service/ssm/ssmiface"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// SSMPrefix starts references to SSM parameters, e.g. "ssm:/prod/sftp/password". Parameters are decrypted.
+	SSMPrefix = "ssm:"
+	// SecretsManagerPrefix starts references to Secrets Manager secrets, e.g. "secretsmanager:bank-keys".
+	// A field after # reads one field of a JSON secret, e.g. "secretsmanager:bank-keys#private".
+	SecretsManagerPrefix = "secretsmanager:"
+)
+
+// DefaultTTL is how long a Resolver caches secrets if TTL is not set.
+const DefaultTTL = 5 * time.Minute
+
+type cached struct {
+	value   string
+	expires time.Time
+}
+
+// Resolver resolves secret references, and caches the secrets for TTL. It implements config.SecretResolver.
+type Resolver struct {
+	ssm            ssmiface.SSMAPI
+	secretsManager secretsmanageriface.SecretsManagerAPI
+	TTL            time.Duration
+
+	now   func() time.Time
+	lock  sync.Mutex
+	cache map[string]cached
+}
+
+// NewResolver creates a Resolver with the shared AWS config. Pass an aws.Config to change it,
+// e.g. &aws.Config{Endpoint: aws.String("http://localhost:4566")} to use a local stand-in for the AWS APIs.
+func NewResolver(configs ...*aws.Config) *Resolver {
+	log.Trace("Setting up secrets resolver")
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+	}))
+	return NewResolverWithClients(ssm.New(sess, configs...), secretsmanager.New(sess, configs...))
+}
+
+// NewResolverWithClients creates a Resolver with existing SSM and Secrets Manager clients.
+func NewResolverWithClients(ssmClient ssmiface.SSMAPI, secretsManager secretsmanageriface.SecretsManagerAPI) *Resolver {
+	return &Resolver{
+		ssm:            ssmClient,
+		secretsManager: secretsManager,
+		TTL:            DefaultTTL,
+		now:            time.Now,
+		cache:          map[string]cached{},
+	}
+}
+
+// IsReference reports whether the value is a secret reference.
+func IsReference(value string) bool {
+	return strings.HasPrefix(value, SSMPrefix) || strings.HasPrefix(value, SecretsManagerPrefix)
+}
+
+// Resolve returns the secret a reference points to, or ok false if value is not a reference.
+func (r *Resolver) Resolve(value string) (secret string, ok bool, err error) {
+	if !IsReference(value) {
+		return "", false, nil
+	}
+
+	r.lock.Lock()
+	c, found := r.cache[value]
+	r.lock.Unlock()
+	if found && r.now().Before(c.expires) {
+		return c.value, true, nil
+	}
+
+	if strings.HasPrefix(value, SSMPrefix) {
+		secret, err = r.parameter(strings.TrimPrefix(value, SSMPrefix))
+	} else {
+		secret, err = r.secret(strings.TrimPrefix(value, SecretsManagerPrefix))
+	}
+	if err != nil {
+		return "", true, err
+	}
+
+	r.lock.Lock()
+	r.cache[value] = cached{value: secret, expires: r.now().Add(r.TTL)}
+	r.lock.Unlock()
+	return secret, true, nil
+}
+
+// Reload drops the cached secrets, so they are fetched again when they are next resolved.
+func (r *Resolver) Reload() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cache = map[string]cached{}
+	return nil
+}
+
+func (r *Resolver) parameter(name string) (string, error) {
+	log.Debugf("Getting SSM parameter %s", name)
+	out, err := r.ssm.GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "could not get SSM parameter %s", name)
+	}
+	if out.Parameter == nil {
+		return "", fmt.Errorf("SSM parameter %s has no value", name)
+	}
+	return aws.StringValue(out.Parameter.Value), nil
+}
+
+func (r *Resolver) secret(ref string) (string, error) {
+	id, field, hasField := strings.Cut(ref, "#")
+	log.Debugf("Getting secret %s", id)
+	out, err := r.secretsManager.GetSecretValue(&secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if err != nil {
+		return "", errors.Wrapf(err, "could not get secret %s", id)
+	}
+
+	value := aws.StringValue(out.SecretString)
+	if out.SecretString == nil {
+		value = string(out.SecretBinary)
+	}
+	if !hasField {
+		return value, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", errors.Wrapf(err, "secret %s is not a JSON object, so it has no field %s", id, field)
+	}
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no field %s", id, field)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(v), nil
+}
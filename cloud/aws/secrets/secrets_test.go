@@ -0,0 +1,138 @@
+package secrets
+
+import (
+	"encoding/json"
+	"github.com/Direct-Debit/go-commons/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// standIn serves GetParameter and GetSecretValue like the AWS JSON APIs, and counts the calls.
+func standIn(t *testing.T, calls *int32) *Resolver {
+	parameters := map[string]string{"/prod/sftp/password": "hunter2"}
+	secrets := map[string]string{"bank-keys": `{"private": "KEY", "version": 3}`, "plain": "just text"}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		var in map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&in))
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+
+		var out map[string]interface{}
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSSM.GetParameter":
+			assert.Equal(t, true, in["WithDecryption"])
+			if v, ok := parameters[in["Name"].(string)]; ok {
+				out = map[string]interface{}{"Parameter": map[string]interface{}{"Name": in["Name"], "Value": v}}
+			}
+		case "secretsmanager.GetSecretValue":
+			if v, ok := secrets[in["SecretId"].(string)]; ok {
+				out = map[string]interface{}{"Name": in["SecretId"], "SecretString": v}
+			}
+		}
+		if out == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			out = map[string]interface{}{"__type": "ResourceNotFoundException", "message": "not found"}
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(out))
+	}))
+	t.Cleanup(srv.Close)
+
+	return NewResolver(&aws.Config{
+		Endpoint:    aws.String(srv.URL),
+		Region:      aws.String("af-south-1"),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+}
+
+func TestResolve(t *testing.T) {
+	var calls int32
+	r := standIn(t, &calls)
+
+	v, ok, err := r.Resolve("ssm:/prod/sftp/password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "hunter2", v)
+
+	v, _, err = r.Resolve("secretsmanager:bank-keys#private")
+	assert.NoError(t, err)
+	assert.Equal(t, "KEY", v)
+	v, _, _ = r.Resolve("secretsmanager:bank-keys#version")
+	assert.Equal(t, "3", v)
+	v, _, _ = r.Resolve("secretsmanager:plain")
+	assert.Equal(t, "just text", v)
+
+	_, ok, err = r.Resolve("plain value")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestResolveErrors(t *testing.T) {
+	var calls int32
+	r := standIn(t, &calls)
+
+	_, ok, err := r.Resolve("ssm:/missing")
+	assert.True(t, ok)
+	assert.Error(t, err)
+	_, _, err = r.Resolve("secretsmanager:bank-keys#missing")
+	assert.Error(t, err)
+	_, _, err = r.Resolve("secretsmanager:plain#field")
+	assert.Error(t, err)
+}
+
+func TestResolveCache(t *testing.T) {
+	var calls int32
+	r := standIn(t, &calls)
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	r.TTL = time.Minute
+
+	for i := 0; i < 3; i++ {
+		_, _, err := r.Resolve("ssm:/prod/sftp/password")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	now = now.Add(time.Minute)
+	_, _, _ = r.Resolve("ssm:/prod/sftp/password")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls), "expired secrets are fetched again")
+
+	assert.NoError(t, r.Reload())
+	_, _, _ = r.Resolve("ssm:/prod/sftp/password")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls), "Reload drops the cache")
+}
+
+func TestConfigSecrets(t *testing.T) {
+	var calls int32
+	p := config.NewSecrets(config.Defaults{
+		"sftp.password":       "ssm:/prod/sftp/password",
+		"sftp.user":           "debits",
+		"pagerduty.keys":      []interface{}{"secretsmanager:bank-keys#private", "plain"},
+		"pagerduty.broken":    "ssm:/missing",
+		"pagerduty.intervals": int64(5),
+	}, standIn(t, &calls))
+
+	v, err := p.Get("sftp.password")
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+	assert.True(t, p.IsSecret("sftp.password"))
+
+	v, _ = p.Get("sftp.user")
+	assert.Equal(t, "debits", v)
+	assert.False(t, p.IsSecret("sftp.user"))
+
+	v, _ = p.GetDef("pagerduty.keys", nil)
+	assert.Equal(t, []interface{}{"KEY", "plain"}, v)
+	v, _ = p.Get("pagerduty.intervals")
+	assert.Equal(t, int64(5), v)
+
+	_, err = p.Get("pagerduty.broken")
+	assert.Error(t, err)
+}
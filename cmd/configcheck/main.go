@@ -0,0 +1,23 @@
+// Command configcheck prints the effective config of a service, with the source of every value,
+// or checks that every config source can be read:
+//
+//	configcheck -file config.toml -env APP print
+//	configcheck -file values.yaml -dynamo-table config validate
+//
+// Without a config struct, print only redacts keys named like secrets, such as "db.password",
+// and the keys listed with -secret-keys:
+//
+//	configcheck -env APP -secret-keys signing,pagerduty.service print
+//
+// To validate against a service's config struct too, and redact the fields with a `secret:"true"` tag,
+// call configcmd.Run from the service's own binary.
+package main
+
+import (
+	"github.com/Direct-Debit/go-commons/config/configcmd"
+	"os"
+)
+
+func main() {
+	os.Exit(configcmd.Run(os.Args[1:], nil, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,397 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/pelletier/go-toml"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrRequired is wrapped by the KeyError of a required key without a value.
+var ErrRequired = errors.New("required but not configured")
+
+// KeyError is a problem with one config key found by Bind.
+type KeyError struct {
+	Key   string // The config key, e.g. "db.port"
+	Field string // The struct field, e.g. "DB.Port"
+	Err   error
+}
+
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Key, e.Field, e.Err)
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// BindErrors are all the problems Bind found.
+type BindErrors []*KeyError
+
+func (e BindErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, k := range e {
+		messages[i] = k.Error()
+	}
+	return fmt.Sprintf("config has %d problem(s): %s", len(e), strings.Join(messages, "; "))
+}
+
+// Bind fills the struct that target points to from the current Provider. See BindFrom.
+func Bind(target interface{}) error {
+	return BindFrom(GetProvider(), target)
+}
+
+// BindFrom fills the struct that target points to from the provider, and returns BindErrors with every problem,
+// so a service can report all of its configuration mistakes at startup instead of panicking on the first one.
+//
+// Fields are read from the key in their `config` tag, or their name in snake case, e.g. LogLevel from "log_level".
+// A tag of "-" skips the field. Fields can have a `default` tag, written like an environment variable,
+// a `required:"true"` tag, and a `secret:"true"` tag for SecretKeys. Nested structs read a table,
+// so DB.Host reads "db.host":
+//
+//	type Config struct {
+//		LogLevel string `config:"log_level" default:"INFO"`
+//		DB       struct {
+//			Host    string        `required:"true"`
+//			Port    int           `default:"5432"`
+//			Timeout time.Duration `default:"5s"`
+//		} `config:"db"`
+//		Banks []string
+//	}
+//
+// Values are converted where it is safe: a TOML int can fill a float, and strings, e.g. from the environment,
+// are parsed for numbers, booleans, durations and comma separated lists. Durations must be strings like "1m30s".
+func BindFrom(p Provider, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config can only be bound to a pointer to a struct, not %T", target)
+	}
+
+	var errs BindErrors
+	bindStruct(p, v.Elem(), "", "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+func bindStruct(p Provider, v reflect.Value, keyPrefix, fieldPrefix string, errs *BindErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, ok := fieldKey(f)
+		if !ok {
+			continue
+		}
+		key = keyPrefix + key
+		field := fieldPrefix + f.Name
+		fv := v.Field(i)
+
+		if f.Type.Kind() == reflect.Struct && f.Type != timeType {
+			bindStruct(p, fv, key+".", field+".", errs)
+			continue
+		}
+
+		raw, err := p.GetDef(key, nil)
+		if err == nil && raw == nil {
+			if def, ok := f.Tag.Lookup("default"); ok {
+				raw = def
+			} else if f.Tag.Get("required") == "true" {
+				err = ErrRequired
+			}
+		}
+		if err == nil && raw != nil {
+			err = assign(fv, raw)
+		}
+		if err != nil {
+			*errs = append(*errs, &KeyError{Key: key, Field: field, Err: err})
+		}
+	}
+}
+
+// fieldKey returns the key of a struct field, relative to its table, and false if the field is skipped.
+func fieldKey(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	key := f.Tag.Get("config")
+	if key == "-" {
+		return "", false
+	}
+	if key == "" {
+		key = snakeCase(f.Name)
+	}
+	return key, true
+}
+
+// SecretKeys returns the keys of the fields of a config struct, or a pointer to one, that have a `secret:"true"` tag.
+// A slice of structs is secret as a whole if its structs have a secret field. Mark them secret for Describe with:
+//
+//	config.MarkSecret(config.SecretKeys(&Config{})...)
+func SecretKeys(schema interface{}) []string {
+	t := reflect.TypeOf(schema)
+	if t == nil {
+		return nil
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return structSecretKeys(t, "")
+}
+
+func structSecretKeys(t reflect.Type, keyPrefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key, ok := fieldKey(f)
+		if !ok {
+			continue
+		}
+		key = keyPrefix + key
+
+		switch {
+		case f.Tag.Get("secret") == "true":
+			keys = append(keys, key)
+		case f.Type.Kind() == reflect.Struct && f.Type != timeType:
+			keys = append(keys, structSecretKeys(f.Type, key+".")...)
+		case f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct && f.Type.Elem() != timeType:
+			if len(structSecretKeys(f.Type.Elem(), "")) > 0 {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// assign converts raw, a value from a Provider, to the type of v and sets it.
+func assign(v reflect.Value, raw interface{}) error {
+	if s, ok := raw.(string); ok {
+		raw = strings.TrimSpace(s)
+	}
+
+	switch {
+	case v.Type() == durationType:
+		s, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("%v is not a duration like \"30s\"", raw)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case v.Type() == timeType:
+		switch r := raw.(type) {
+		case time.Time:
+			v.Set(reflect.ValueOf(r))
+		case string:
+			t, err := time.Parse(time.RFC3339, r)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(t))
+		default:
+			return fmt.Errorf("%v is not a time", raw)
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		switch r := raw.(type) {
+		case string:
+			v.SetString(r)
+		case int64, int, float64, bool:
+			v.SetString(fmt.Sprint(r))
+		default:
+			return fmt.Errorf("%v is not a string", raw)
+		}
+	case reflect.Bool:
+		switch r := raw.(type) {
+		case bool:
+			v.SetBool(r)
+		case string:
+			b, err := strconv.ParseBool(r)
+			if err != nil {
+				return fmt.Errorf("%q is not a boolean", r)
+			}
+			v.SetBool(b)
+		default:
+			return fmt.Errorf("%v is not a boolean", raw)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := toInt64(raw)
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("%d does not fit in %s", i, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := toInt64(raw)
+		if err != nil {
+			return err
+		}
+		if i < 0 || v.OverflowUint(uint64(i)) {
+			return fmt.Errorf("%d does not fit in %s", i, v.Type())
+		}
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch r := raw.(type) {
+		case float64:
+			f = r
+		case int64:
+			f = float64(r)
+		case int:
+			f = float64(r)
+		case string:
+			var err error
+			if f, err = strconv.ParseFloat(r, 64); err != nil {
+				return fmt.Errorf("%q is not a number", r)
+			}
+		default:
+			return fmt.Errorf("%v is not a number", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		return assignSlice(v, raw)
+	default:
+		return fmt.Errorf("config cannot fill fields of type %s", v.Type())
+	}
+	return nil
+}
+
+func toInt64(raw interface{}) (int64, error) {
+	switch r := raw.(type) {
+	case int64:
+		return r, nil
+	case int:
+		return int64(r), nil
+	case float64:
+		if r != math.Trunc(r) || r < math.MinInt64 || r >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v is not a whole number", r)
+		}
+		return int64(r), nil
+	case string:
+		i, err := strconv.ParseInt(r, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not an integer", r)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("%v is not an integer", raw)
+	}
+}
+
+// assignSlice fills a slice from a list, a comma separated string, or an array of TOML tables for a slice of structs.
+func assignSlice(v reflect.Value, raw interface{}) error {
+	var items []interface{}
+	switch r := raw.(type) {
+	case []interface{}:
+		items = r
+	case []string:
+		for _, s := range r {
+			items = append(items, s)
+		}
+	case []*toml.Tree:
+		for _, t := range r {
+			items = append(items, t)
+		}
+	case []map[string]interface{}:
+		for _, m := range r {
+			items = append(items, m)
+		}
+	case string:
+		if r != "" {
+			for _, s := range strings.Split(r, ",") {
+				items = append(items, s)
+			}
+		}
+	default:
+		return fmt.Errorf("%v is not a list", raw)
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+	var problems []string
+	for i, item := range items {
+		elem := slice.Index(i)
+		var err error
+		if elem.Kind() == reflect.Struct && elem.Type() != timeType {
+			err = assignTable(elem, item)
+		} else {
+			err = assign(elem, item)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("item %d: %v", i, err))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+	v.Set(slice)
+	return nil
+}
+
+// assignTable fills a struct in a slice from a TOML table or a map.
+func assignTable(v reflect.Value, raw interface{}) error {
+	table := Defaults{}
+	switch r := raw.(type) {
+	case *toml.Tree:
+		flatten(table, "", r.ToMap())
+	case map[string]interface{}:
+		flatten(table, "", r)
+	default:
+		return fmt.Errorf("%v is not a table", raw)
+	}
+
+	var errs BindErrors
+	bindStruct(table, v, "", "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// flatten copies a nested map into flat, with dotted keys like "db.host".
+func flatten(flat Defaults, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			flatten(flat, prefix+k+".", nested)
+			continue
+		}
+		flat[prefix+k] = v
+	}
+}
+
+// snakeCase converts a field name like LogLevel or DBHost to log_level or db_host.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (nextLower && unicode.IsUpper(runes[i-1])) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const bindTestToml = `
+log_level = "INFO"
+rate = 2
+
+[db]
+host = "db.local"
+timeout = "1m30s"
+
+[[banks]]
+name = "absa"
+branch = 632005
+
+[[banks]]
+name = "fnb"
+branch = 250655
+`
+
+type bindTestConfig struct {
+	LogLevel string
+	Rate     float64
+	Retries  int      `default:"3"`
+	Tags     []string `default:"a,b"`
+	Ignored  string   `config:"-"`
+	DB       struct {
+		Host    string        `required:"true"`
+		Port    uint16        `default:"5432"`
+		Timeout time.Duration `default:"5s"`
+	} `config:"db"`
+	Banks []struct {
+		Name   string
+		Branch string
+	}
+}
+
+func TestBindFrom(t *testing.T) {
+	file, _ := testToml(t, bindTestToml)
+	var c bindTestConfig
+	err := BindFrom(file, &c)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "INFO", c.LogLevel)
+	assert.Equal(t, 2.0, c.Rate, "a TOML int fills a float")
+	assert.Equal(t, 3, c.Retries)
+	assert.Equal(t, []string{"a", "b"}, c.Tags)
+	assert.Equal(t, "db.local", c.DB.Host)
+	assert.Equal(t, uint16(5432), c.DB.Port)
+	assert.Equal(t, 90*time.Second, c.DB.Timeout)
+	if assert.Len(t, c.Banks, 2) {
+		assert.Equal(t, "fnb", c.Banks[1].Name)
+		assert.Equal(t, "250655", c.Banks[1].Branch)
+	}
+}
+
+func TestBindFromEnvLayer(t *testing.T) {
+	t.Setenv("BIND_TEST_DB_PORT", "6543")
+	t.Setenv("BIND_TEST_TAGS", "x, y")
+	t.Setenv("BIND_TEST_DB_TIMEOUT", "10s")
+	file, _ := testToml(t, bindTestToml)
+	l := NewLayered(
+		Layer{Name: "env", Provider: NewEnv("BIND_TEST")},
+		Layer{Name: "file", Provider: file},
+	)
+
+	var c bindTestConfig
+	assert.NoError(t, BindFrom(l, &c))
+	assert.Equal(t, uint16(6543), c.DB.Port)
+	assert.Equal(t, []string{"x", "y"}, c.Tags)
+	assert.Equal(t, 10*time.Second, c.DB.Timeout)
+}
+
+func TestBindFromReportsAllProblems(t *testing.T) {
+	p, _ := testToml(t, `
+log_level = 5
+rate = "fast"
+retries = 1.5
+
+[db]
+port = 70000
+timeout = 30
+`)
+	var c bindTestConfig
+	err := BindFrom(p, &c)
+
+	var errs BindErrors
+	if assert.True(t, errors.As(err, &errs)) {
+		keys := make([]string, len(errs))
+		for i, e := range errs {
+			keys[i] = e.Key
+		}
+		assert.Equal(t, []string{"rate", "retries", "db.host", "db.port", "db.timeout"}, keys)
+		assert.ErrorIs(t, errs[2], ErrRequired)
+		assert.Equal(t, "DB.Host", errs[2].Field)
+	}
+	assert.Equal(t, "5", c.LogLevel, "numbers can fill strings")
+}
+
+func TestBindFromNeedsStructPointer(t *testing.T) {
+	var c bindTestConfig
+	assert.Error(t, BindFrom(Defaults{}, c))
+	assert.Error(t, BindFrom(Defaults{}, nil))
+}
+
+func TestSnakeCase(t *testing.T) {
+	assert.Equal(t, "log_level", snakeCase("LogLevel"))
+	assert.Equal(t, "db_host", snakeCase("DBHost"))
+	assert.Equal(t, "db", snakeCase("DB"))
+	assert.Equal(t, "retries", snakeCase("Retries"))
+	assert.Equal(t, "s3_bucket", snakeCase("S3Bucket"))
+}
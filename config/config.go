@@ -6,6 +6,7 @@ import (
 	"github.com/Direct-Debit/go-commons/stdext"
 	log "github.com/sirupsen/logrus"
 	"strings"
+	"sync"
 )
 
 type Provider interface {
@@ -14,24 +15,39 @@ type Provider interface {
 	Get(key string) (interface{}, error)
 }
 
-var conf Provider = tomlold.NewReader()
+var (
+	confLock sync.RWMutex
+	conf     Provider = tomlold.NewReader()
+)
 
-func SetProvider(c Provider) { conf = c }
+func SetProvider(c Provider) {
+	confLock.Lock()
+	defer confLock.Unlock()
+	conf = c
+}
 
-func GetProvider() Provider { return conf }
+func GetProvider() Provider {
+	confLock.RLock()
+	defer confLock.RUnlock()
+	return conf
+}
 
+// Reload reloads the provider, and calls the OnChange functions of the keys that changed.
 func Reload() error {
-	return conf.Reload()
+	p := GetProvider()
+	err := p.Reload()
+	notifyChanges(p)
+	return err
 }
 
 func GetDef(key string, def interface{}) interface{} {
-	val, err := conf.GetDef(key, def)
+	val, err := GetProvider().GetDef(key, def)
 	errlib.DebugError(err, "Error reading %s config setting (defaulting to %v)", key, def)
 	return val
 }
 
 func Get(key string) interface{} {
-	v, err := conf.Get(key)
+	v, err := GetProvider().Get(key)
 	errlib.FatalError(err, "Error reading %s config setting", key)
 	return v
 }
@@ -0,0 +1,214 @@
+// Package configcmd is a command line tool to print the effective config of a service, with the source of every
+// value, and to validate it against the service's config struct. A service can add it to its own binary:
+//
+//	func main() {
+//		os.Exit(configcmd.Run(os.Args[1:], &MyConfig{}, os.Stdout, os.Stderr))
+//	}
+//
+// cmd/configcheck runs it without a struct, to print config and check that every source can be read.
+package configcmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/Direct-Debit/go-commons/cloud/aws/dynamo"
+	"github.com/Direct-Debit/go-commons/cloud/aws/secrets"
+	"github.com/Direct-Debit/go-commons/config"
+	"github.com/Direct-Debit/go-commons/config/fileconf"
+	"io"
+	"strings"
+)
+
+// Exit codes of Run.
+const (
+	ExitOK      = 0
+	ExitInvalid = 1 // The config could not be read, or failed validation
+	ExitUsage   = 2
+)
+
+const usage = `Usage: %s [flags] [print|validate]
+
+print     lists every config key with its value and source, with secrets redacted (the default)
+validate  checks that every source can be read, and that the config fills the service's config struct
+
+Flags:
+`
+
+// errUnreadable is returned by provider when a config source that exists could not be read.
+var errUnreadable = errors.New("could not read config")
+
+// newDynamoConfig is dynamo.NewConfig, replaced in tests.
+var newDynamoConfig = dynamo.NewConfig
+
+type options struct {
+	file        string
+	envPrefix   string
+	dynamoTable string
+	dynamoKey   string
+	secrets     bool
+	secretKeys  string
+	json        bool
+}
+
+// Run runs the tool with the command line args, without the program name, and returns the exit code.
+// Schema is a pointer to the config struct to validate with config.BindFrom, or nil.
+// Print redacts the keys of its fields with a `secret:"true"` tag, see config.SecretKeys,
+// and the keys in the -secret-keys flag.
+func Run(args []string, schema interface{}, stdout, stderr io.Writer) int {
+	var o options
+	flags := flag.NewFlagSet("config", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, usage, flags.Name())
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&o.file, "file", "config.toml", "config file: .toml, .yaml, .yml, .json or .env, empty for none")
+	flags.StringVar(&o.envPrefix, "env", "", "prefix of environment variables that override the file, e.g. APP")
+	flags.StringVar(&o.dynamoTable, "dynamo-table", "", "DynamoDB config table, below the file")
+	flags.StringVar(&o.dynamoKey, "dynamo-key", "key", "key column of the DynamoDB config table")
+	flags.BoolVar(&o.secrets, "secrets", false, "resolve ssm: and secretsmanager: references")
+	flags.StringVar(&o.secretKeys, "secret-keys", "", "comma separated keys to redact, besides the ones named like secrets")
+	flags.BoolVar(&o.json, "json", false, "print JSON")
+	if err := flags.Parse(args); err != nil {
+		return ExitUsage
+	}
+
+	command := "print"
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		command = flags.Arg(0)
+	default:
+		flags.Usage()
+		return ExitUsage
+	}
+	if command != "print" && command != "validate" {
+		fmt.Fprintf(stderr, "Unknown command %s\n", command)
+		flags.Usage()
+		return ExitUsage
+	}
+
+	p, err := o.provider()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		if errors.Is(err, errUnreadable) {
+			return ExitInvalid
+		}
+		return ExitUsage
+	}
+	if command == "validate" {
+		return validate(p, schema, stdout, stderr)
+	}
+	if schema != nil {
+		config.MarkSecret(config.SecretKeys(schema)...)
+	}
+	for _, key := range strings.Split(o.secretKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			config.MarkSecret(key)
+		}
+	}
+	return printConfig(p, o.json, stdout, stderr)
+}
+
+// provider builds the layers in the order services use: env, then the file, then DynamoDB.
+func (o options) provider() (config.Provider, error) {
+	var layers []config.Layer
+	if o.envPrefix != "" {
+		layers = append(layers, config.Layer{Name: "env", Provider: config.NewEnv(o.envPrefix)})
+	}
+	if o.file != "" {
+		f, err := fileconf.Open(o.file)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, config.Layer{Name: "file", Provider: f})
+	}
+	if o.dynamoTable != "" {
+		d, err := newDynamoConfig(o.dynamoTable, o.dynamoKey, nil)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errUnreadable, err)
+		}
+		layers = append(layers, config.Layer{Name: "dynamo", Provider: d})
+	}
+	if len(layers) == 0 {
+		return nil, errors.New("no config sources: use -file, -env or -dynamo-table")
+	}
+
+	var p config.Provider = config.NewLayered(layers...)
+	if o.secrets {
+		p = config.NewSecrets(p, secrets.NewResolver())
+	}
+	return p, nil
+}
+
+// printConfig prints the settings of p. Sources that can't be read, like a missing file, are reported
+// and make it return ExitInvalid, since Describe would silently leave their keys out.
+func printConfig(p config.Provider, asJSON bool, stdout, stderr io.Writer) int {
+	reloadErr := p.Reload()
+	settings, err := config.Describe(p)
+	if asJSON {
+		type setting struct {
+			Key    string      `json:"key"`
+			Value  interface{} `json:"value"`
+			Source string      `json:"source"`
+			Secret bool        `json:"secret,omitempty"`
+		}
+		out := make([]setting, len(settings))
+		for i, s := range settings {
+			out[i] = setting{Key: s.Key, Value: s.Value, Source: s.Source, Secret: s.Secret}
+		}
+		e := json.NewEncoder(stdout)
+		e.SetIndent("", "  ")
+		if encErr := e.Encode(out); encErr != nil {
+			fmt.Fprintln(stderr, encErr)
+			return ExitInvalid
+		}
+	} else {
+		for _, s := range settings {
+			fmt.Fprintln(stdout, s)
+		}
+	}
+
+	failed := false
+	for _, e := range []error{reloadErr, err} {
+		if e != nil {
+			fmt.Fprintln(stderr, e)
+			failed = true
+		}
+	}
+	if failed {
+		return ExitInvalid
+	}
+	return ExitOK
+}
+
+func validate(p config.Provider, schema interface{}, stdout, stderr io.Writer) int {
+	ok := true
+	if err := p.Reload(); err != nil {
+		fmt.Fprintln(stderr, err)
+		ok = false
+	}
+	if schema != nil {
+		err := config.BindFrom(p, schema)
+		var problems config.BindErrors
+		switch {
+		case errors.As(err, &problems):
+			for _, problem := range problems {
+				fmt.Fprintln(stderr, problem)
+			}
+			ok = false
+		case err != nil:
+			fmt.Fprintln(stderr, err)
+			ok = false
+		}
+	}
+
+	if !ok {
+		fmt.Fprintln(stderr, "config is not valid")
+		return ExitInvalid
+	}
+	fmt.Fprintln(stdout, "config is valid")
+	return ExitOK
+}
@@ -0,0 +1,126 @@
+package configcmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/Direct-Debit/go-commons/cloud/aws/dynamo"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type schema struct {
+	LogLevel string `required:"true"`
+	DB       struct {
+		Host string `required:"true"`
+		Port int    `default:"5432"`
+	}
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func run(args []string, s interface{}) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(args, s, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestPrint(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "log_level: INFO\ndb:\n  host: db.local\n  password: hunter2\n")
+	t.Setenv("CONFIGCMD_TEST_LOG_LEVEL", "DEBUG")
+
+	code, out, errOut := run([]string{"-file", path, "-env", "CONFIGCMD_TEST"}, nil)
+	assert.Equal(t, ExitOK, code, errOut)
+	assert.Equal(t, `db.host = "db.local" (file)
+db.password = ******** (file)
+log_level = "DEBUG" (env)
+`, out)
+
+	code, out, _ = run([]string{"-file", path, "-json", "print"}, nil)
+	assert.Equal(t, ExitOK, code)
+	var settings []map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(out), &settings))
+	assert.Len(t, settings, 3)
+	assert.Equal(t, true, settings[1]["secret"])
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "signing: abc\nlog_level: INFO\n")
+	t.Setenv("CONFIGCMD_SECRET_PAGERDUTY_ROUTING_KEY", "xyz")
+	var s struct {
+		Signing  string `secret:"true"`
+		LogLevel string
+	}
+
+	code, out, errOut := run([]string{"-file", path, "-env", "CONFIGCMD_SECRET"}, &s)
+	assert.Equal(t, ExitOK, code, errOut)
+	assert.Equal(t, `log_level = "INFO" (file)
+pagerduty.routing.key = ******** (env)
+signing = ******** (file)
+`, out)
+}
+
+func TestPrintMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	code, _, errOut := run([]string{"-file", path, "print"}, nil)
+	assert.Equal(t, ExitInvalid, code)
+	assert.Contains(t, errOut, "config.yaml")
+}
+
+func TestPrintSecretKeysFlag(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "webhook: abc\nlog_level: INFO\n")
+
+	code, out, errOut := run([]string{"-file", path, "-secret-keys", "webhook, other", "print"}, nil)
+	assert.Equal(t, ExitOK, code, errOut)
+	assert.Equal(t, `log_level = "INFO" (file)
+webhook = ******** (file)
+`, out)
+}
+
+func TestDynamoFails(t *testing.T) {
+	defer func(f func(string, string, dynamodbiface.DynamoDBAPI) (dynamo.Config, error)) { newDynamoConfig = f }(newDynamoConfig)
+	newDynamoConfig = func(string, string, dynamodbiface.DynamoDBAPI) (dynamo.Config, error) {
+		return dynamo.Config{}, errors.New("no table")
+	}
+
+	code, _, errOut := run([]string{"-file", "", "-dynamo-table", "config"}, nil)
+	assert.Equal(t, ExitInvalid, code)
+	assert.Contains(t, errOut, "no table")
+}
+
+func TestValidate(t *testing.T) {
+	valid := writeConfig(t, "config.toml", "log_level = \"INFO\"\n[db]\nhost = \"db.local\"\n")
+	code, out, _ := run([]string{"-file", valid, "validate"}, &schema{})
+	assert.Equal(t, ExitOK, code)
+	assert.Equal(t, "config is valid\n", out)
+
+	invalid := writeConfig(t, "config.json", `{"db": {"port": "many"}}`)
+	code, _, errOut := run([]string{"-file", invalid, "validate"}, &schema{})
+	assert.Equal(t, ExitInvalid, code)
+	assert.Contains(t, errOut, "log_level (LogLevel)")
+	assert.Contains(t, errOut, "db.host (DB.Host)")
+	assert.Contains(t, errOut, "db.port (DB.Port)")
+
+	missing := filepath.Join(t.TempDir(), "missing.toml")
+	code, _, _ = run([]string{"-file", missing, "validate"}, nil)
+	assert.Equal(t, ExitInvalid, code)
+}
+
+func TestUsage(t *testing.T) {
+	code, _, _ := run([]string{"-file", "config.toml", "explode"}, nil)
+	assert.Equal(t, ExitUsage, code)
+	code, _, _ = run([]string{"-file", "config.ini"}, nil)
+	assert.Equal(t, ExitUsage, code)
+	code, _, _ = run([]string{"-file", ""}, nil)
+	assert.Equal(t, ExitUsage, code)
+	code, _, _ = run([]string{"-nope"}, nil)
+	assert.Equal(t, ExitUsage, code)
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the values of secret keys in Describe.
+const Redacted = "********"
+
+// KeyLister is implemented by providers that can list their keys, like "db.host", for Describe.
+type KeyLister interface {
+	Keys() []string
+}
+
+// Setting is a resolved config value and where it came from.
+type Setting struct {
+	Key    string
+	Value  interface{} // Redacted if the key is secret
+	Source string      // The name of the Layered layer, or of the provider
+	Secret bool
+}
+
+func (s Setting) String() string {
+	if s.Secret {
+		return fmt.Sprintf("%s = %s (%s)", s.Key, s.Value, s.Source)
+	}
+	return fmt.Sprintf("%s = %#v (%s)", s.Key, s.Value, s.Source)
+}
+
+// SecretKeyWords mark keys as secret when the key contains one of them, e.g. "sftp.password".
+// Keys are compared in lower case, with '.' and '_' the same, since EnvKeys turns APP_PAGERDUTY_ROUTING_KEY
+// into "pagerduty.routing.key".
+var SecretKeyWords = []string{"password", "secret", "token", "private", "routing_key", "api_key", "credential"}
+
+var (
+	secretKeysLock sync.RWMutex
+	secretKeys     = map[string]bool{}
+)
+
+// MarkSecret marks keys as secret, so Describe redacts their values. See SecretKeys for the keys of a config struct.
+func MarkSecret(keys ...string) {
+	secretKeysLock.Lock()
+	defer secretKeysLock.Unlock()
+	for _, k := range keys {
+		secretKeys[secretKeyName(k)] = true
+	}
+}
+
+// IsSecretKey reports whether key was marked with MarkSecret, or contains one of SecretKeyWords.
+func IsSecretKey(key string) bool {
+	name := secretKeyName(key)
+	secretKeysLock.RLock()
+	marked := secretKeys[name]
+	secretKeysLock.RUnlock()
+	if marked {
+		return true
+	}
+
+	for _, word := range SecretKeyWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretKeyName is key in lower case with dots replaced by underscores, so a key read from an environment
+// variable matches the same key from a file.
+func secretKeyName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), ".", "_")
+}
+
+// Describe lists every key the provider knows, sorted, with its value and source, to see which value came from where:
+//
+//	settings, err := config.Describe(config.GetProvider())
+//	for _, s := range settings {
+//		log.Info(s)
+//	}
+//
+// Only providers that implement KeyLister can list their keys. The values of secret keys are Redacted:
+// keys that IsSecretKey, and values that a Secrets provider resolved from a secret reference.
+func Describe(p Provider) ([]Setting, error) {
+	keys := listKeys(p)
+	settings := make([]Setting, 0, len(keys))
+	var failed []string
+	for _, key := range keys {
+		v, source, err := describeKey(p, key)
+		if err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		if v == nil {
+			continue
+		}
+		s := Setting{Key: key, Value: v, Source: source}
+		if IsSecretKey(key) || isSecretValue(p, key) {
+			s.Value, s.Secret = Redacted, true
+		}
+		settings = append(settings, s)
+	}
+	if len(failed) > 0 {
+		return settings, fmt.Errorf("could not read config: %s", strings.Join(failed, "; "))
+	}
+	return settings, nil
+}
+
+func listKeys(p Provider) []string {
+	var keys []string
+	if l, ok := p.(KeyLister); ok {
+		keys = l.Keys()
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// describeKey returns the value of key, and the layer or provider it came from.
+func describeKey(p Provider, key string) (interface{}, string, error) {
+	switch p := p.(type) {
+	case *Layered:
+		return p.lookup(key, nil)
+	case *Secrets:
+		v, err := p.GetDef(key, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		_, source, err := describeKey(p.provider, key)
+		return v, source, err
+	default:
+		v, err := p.GetDef(key, nil)
+		return v, SourceName(p), err
+	}
+}
+
+func isSecretValue(p Provider, key string) bool {
+	switch p := p.(type) {
+	case *Secrets:
+		return p.IsSecret(key) || isSecretValue(p.provider, key)
+	case *Layered:
+		_, source, _ := p.lookup(key, nil)
+		for _, layer := range p.layers {
+			if layer.Name == source {
+				return isSecretValue(layer.Provider, key)
+			}
+		}
+	}
+	return false
+}
+
+// SourceName names a provider that is not in a Layered provider: "env", "default", the path of a file,
+// or the provider's type.
+func SourceName(p Provider) string {
+	switch p := p.(type) {
+	case *Env:
+		return "env"
+	case Defaults:
+		return "default"
+	case *Secrets:
+		return SourceName(p.provider)
+	case interface{ Path() string }:
+		return p.Path()
+	default:
+		return reflect.TypeOf(p).String()
+	}
+}
+
+// Keys returns the keys of all the layers. Environment variables that override a key of a lower layer are listed
+// with that key. Other variables are listed with the key their name maps back to, e.g. "db.host" for APP_DB_HOST.
+func (l *Layered) Keys() []string {
+	set := map[string]bool{}
+	for _, layer := range l.layers {
+		if _, ok := layer.Provider.(*Env); ok {
+			continue
+		}
+		for _, k := range listKeys(layer.Provider) {
+			set[k] = true
+		}
+	}
+	for _, layer := range l.layers {
+		env, ok := layer.Provider.(*Env)
+		if !ok {
+			continue
+		}
+		names := map[string]bool{}
+		for k := range set {
+			names[env.Name(k)] = true
+		}
+		for _, k := range env.Keys() {
+			if !names[env.Name(k)] {
+				set[k] = true
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Keys returns the keys of the environment variables that start with the prefix, e.g. "db.host" for APP_DB_HOST.
+// Without a prefix, or with a custom Lookup, there is no way to tell which variables are config, so it returns nil.
+func (e *Env) Keys() []string {
+	if e.Prefix == "" || e.Lookup != nil {
+		return nil
+	}
+	var names []string
+	for _, v := range os.Environ() {
+		name, _, _ := strings.Cut(v, "=")
+		names = append(names, name)
+	}
+	return EnvKeys(e.Prefix, names)
+}
+
+// EnvKeys maps environment variable names that start with prefix back to keys, e.g. APP_DB_HOST to "db.host".
+// Underscores can't be told apart from dots, so APP_LOG_LEVEL becomes "log.level".
+func EnvKeys(prefix string, names []string) []string {
+	if prefix != "" {
+		prefix = strings.TrimSuffix(prefix, "_") + "_"
+	}
+	var keys []string
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) || name == prefix {
+			continue
+		}
+		keys = append(keys, strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, prefix), "_", ".")))
+	}
+	return keys
+}
+
+func (d Defaults) Keys() []string {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (s *Secrets) Keys() []string {
+	return listKeys(s.provider)
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"github.com/Direct-Debit/go-commons/config/tomlold"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) Resolve(value string) (string, bool, error) {
+	secret, ok := f[value]
+	return secret, ok, nil
+}
+
+func TestDescribe(t *testing.T) {
+	file, _ := testToml(t, `
+log_level = "INFO"
+[db]
+host = "db.local"
+port = 5432
+user_pass = "vault:db"
+[sftp]
+password = "plain-text"
+`)
+	t.Setenv("DESCRIBE_TEST_DB_HOST", "env-host")
+	t.Setenv("DESCRIBE_TEST_LOG_LEVEL", "DEBUG")
+	t.Setenv("DESCRIBE_TEST_EXTRA", "1")
+
+	p := NewLayered(
+		Layer{Name: "env", Provider: NewEnv("DESCRIBE_TEST")},
+		Layer{Name: "file", Provider: NewSecrets(file, fakeResolver{"vault:db": "s3cret"})},
+		Layer{Name: "default", Provider: Defaults{"db.timeout": "5s", "db.port": int64(1)}},
+	)
+	settings, err := Describe(p)
+	assert.NoError(t, err)
+
+	var lines []string
+	for _, s := range settings {
+		lines = append(lines, s.String())
+	}
+	assert.Equal(t, []string{
+		`db.host = "env-host" (env)`,
+		`db.port = 5432 (file)`,
+		`db.timeout = "5s" (default)`,
+		`db.user_pass = ******** (file)`,
+		`extra = 1 (env)`,
+		`log_level = "DEBUG" (env)`,
+		`sftp.password = ******** (file)`,
+	}, lines)
+	for _, line := range lines {
+		assert.False(t, strings.Contains(line, "s3cret") || strings.Contains(line, "plain-text"))
+	}
+}
+
+func TestDescribeSingleProvider(t *testing.T) {
+	MarkSecret("describe.marked")
+	settings, err := Describe(Defaults{"describe.marked": "x", "a": int64(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, []Setting{
+		{Key: "a", Value: int64(1), Source: "default"},
+		{Key: "describe.marked", Value: Redacted, Source: "default", Secret: true},
+	}, settings)
+
+	settings, _ = Describe(tomlold.NewReaderWithPath("config.toml"))
+	if assert.NotEmpty(t, settings) {
+		assert.Equal(t, "config.toml", settings[0].Source)
+	}
+}
+
+func TestIsSecretKey(t *testing.T) {
+	assert.True(t, IsSecretKey("sftp.password"))
+	assert.True(t, IsSecretKey("pagerduty.routing_key"))
+	assert.True(t, IsSecretKey("pagerduty.routing.key"), "keys from environment variables have dots for underscores")
+	assert.True(t, IsSecretKey("API_TOKEN"))
+	assert.True(t, IsSecretKey("password_policy.min_length"))
+	assert.False(t, IsSecretKey("db.host"))
+
+	MarkSecret("describe.signing_key")
+	assert.True(t, IsSecretKey("describe.signing.key"))
+	assert.True(t, IsSecretKey("DESCRIBE.SIGNING_KEY"))
+}
+
+func TestSecretKeys(t *testing.T) {
+	type schema struct {
+		Signing string `secret:"true"`
+		DB      struct {
+			Host string
+			Pass string `config:"user_pass" secret:"true"`
+		} `config:"db"`
+		Banks []struct {
+			Name string
+			Key  string `secret:"true"`
+		}
+		Hooks   []struct{ Name string }
+		Skipped string `config:"-" secret:"true"`
+	}
+	assert.Equal(t, []string{"signing", "db.user_pass", "banks"}, SecretKeys(&schema{}))
+	assert.Equal(t, []string{"signing", "db.user_pass", "banks"}, SecretKeys(schema{}))
+	assert.Nil(t, SecretKeys(nil))
+
+	var c struct {
+		Banks []struct {
+			Name string `secret:"true"`
+		}
+	}
+	assert.NoError(t, BindFrom(Defaults{"banks": []interface{}{map[string]interface{}{"name": "absa"}}}, &c))
+	assert.False(t, IsSecretKey("name"), "binding doesn't mark keys secret")
+}
+
+func TestEnvKeys(t *testing.T) {
+	assert.Equal(t, []string{"db.host", "log.level"}, EnvKeys("APP", []string{"APP_DB_HOST", "PATH", "APP_LOG_LEVEL", "APP_"}))
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"github.com/pelletier/go-toml"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Env is a Provider for environment variables. The key "db.host" is read from PREFIX_DB_HOST:
+// the key is upper cased, and dots and hyphens become underscores.
+//
+// On its own, Env guesses the type of a value the way TOML would, so "5" is an int64 and "true" is a bool.
+// In a Layered provider the value gets the type of the same key in the layers below it instead.
+type Env struct {
+	Prefix string
+	// Lookup reads a variable. It defaults to os.LookupEnv.
+	Lookup func(name string) (string, bool)
+}
+
+// NewEnv creates an Env provider for variables starting with prefix, e.g. NewEnv("APP") reads APP_DB_HOST for db.host.
+func NewEnv(prefix string) *Env {
+	return &Env{Prefix: prefix}
+}
+
+// Name returns the name of the environment variable for key.
+func (e *Env) Name(key string) string {
+	name := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+	if e.Prefix == "" {
+		return name
+	}
+	return strings.TrimSuffix(e.Prefix, "_") + "_" + name
+}
+
+func (e *Env) Reload() error {
+	return nil
+}
+
+// Changed is false, since the environment of a running process doesn't change.
+func (e *Env) Changed() bool {
+	return false
+}
+
+func (e *Env) Get(key string) (interface{}, error) {
+	s, ok := e.lookupString(key)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %s is not set", e.Name(key))
+	}
+	return inferValue(s), nil
+}
+
+func (e *Env) GetDef(key string, def interface{}) (interface{}, error) {
+	s, ok := e.lookupString(key)
+	if !ok {
+		return def, nil
+	}
+	if def == nil {
+		return inferValue(s), nil
+	}
+	return convertLike(s, def)
+}
+
+func (e *Env) lookupString(key string) (string, bool) {
+	lookup := e.Lookup
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	return lookup(e.Name(key))
+}
+
+// inferValue reads s as a TOML value, like 5, 1.5, true or ["a", "b"], or returns it as a string if it isn't one.
+func inferValue(s string) interface{} {
+	tree, err := toml.Load("v = " + s)
+	if err != nil {
+		return s
+	}
+	return tree.Get("v")
+}
+
+// convertLike converts s to the type of like, the way the TOML reader returns values.
+// Lists are comma separated, or TOML arrays.
+func convertLike(s string, like interface{}) (interface{}, error) {
+	switch like := like.(type) {
+	case string:
+		return s, nil
+	case int64, int:
+		v, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", s)
+		}
+		if _, ok := like.(int); ok {
+			return int(v), nil
+		}
+		return v, nil
+	case float64:
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return v, nil
+	case bool:
+		v, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", s)
+		}
+		return v, nil
+	case []interface{}:
+		if list, ok := inferValue(s).([]interface{}); ok {
+			return list, nil
+		}
+		var elem interface{} = ""
+		if len(like) > 0 {
+			elem = like[0]
+		}
+		parts := strings.Split(s, ",")
+		list := make([]interface{}, len(parts))
+		for i, p := range parts {
+			v, err := convertLike(strings.TrimSpace(p), elem)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = v
+		}
+		return list, nil
+	case []string:
+		parts := strings.Split(s, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts, nil
+	default:
+		return inferValue(s), nil
+	}
+}
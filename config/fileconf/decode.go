@@ -0,0 +1,89 @@
+package fileconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"math"
+)
+
+func decodeYAML(content []byte) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+	normalized, err := normalize(values)
+	if err != nil {
+		return nil, err
+	}
+	return normalized.(map[string]interface{}), nil
+}
+
+func decodeJSON(content []byte) (map[string]interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(content))
+	d.UseNumber()
+	var values map[string]interface{}
+	if err := d.Decode(&values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		return nil, fmt.Errorf("config file must contain an object")
+	}
+	normalized, err := normalize(values)
+	if err != nil {
+		return nil, err
+	}
+	return normalized.(map[string]interface{}), nil
+}
+
+// normalize converts decoded values to the types tomlold.Reader returns: int64, float64, []interface{} and tables.
+func normalize(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			n, err := normalize(item)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = n
+		}
+		return v, nil
+	case map[interface{}]interface{}:
+		table := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			n, err := normalize(item)
+			if err != nil {
+				return nil, err
+			}
+			table[fmt.Sprint(k)] = n
+		}
+		return table, nil
+	case []interface{}:
+		for i, item := range v {
+			n, err := normalize(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+		return v, nil
+	case int:
+		return int64(v), nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, fmt.Errorf("%d is too large", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,144 @@
+package fileconf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/Direct-Debit/go-commons/config"
+	"github.com/Direct-Debit/go-commons/config/internal/filestate"
+	"strings"
+)
+
+// DotenvReader is a config.Provider for a dotenv file of NAME=value lines. Keys are mapped to names the same way
+// as for config.Env, so "db.host" is read from DB_HOST, and unquoted values are typed the same way too.
+// Quoted values stay strings, so PIN="0123" is not read as a number.
+type DotenvReader struct {
+	*filestate.File[map[string]dotenvVar]
+	env *config.Env
+}
+
+// dotenvVar is the value of a variable in a dotenv file, and whether it was quoted.
+type dotenvVar struct {
+	value  string
+	quoted bool
+}
+
+// NewDotenvReader creates a DotenvReader for the file. Names in the file start with prefix, if it isn't empty.
+func NewDotenvReader(path, prefix string) *DotenvReader {
+	r := &DotenvReader{File: filestate.New(path, parseDotenv)}
+	r.env = &config.Env{Prefix: prefix, Lookup: r.lookupVar}
+	return r
+}
+
+func (r *DotenvReader) lookupVar(name string) (string, bool) {
+	vars, _ := r.Value()
+	v, ok := vars[name]
+	return v.value, ok
+}
+
+// Keys returns the keys of the variables in the file, mapped back from their names like config.EnvKeys does.
+func (r *DotenvReader) Keys() []string {
+	vars, _ := r.Value()
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	return config.EnvKeys(r.env.Prefix, names)
+}
+
+func (r *DotenvReader) Get(key string) (interface{}, error) {
+	v, err := r.GetDef(key, nil)
+	if err == nil && v == nil {
+		return nil, fmt.Errorf("no config value for %s", key)
+	}
+	return v, err
+}
+
+func (r *DotenvReader) GetDef(key string, def interface{}) (interface{}, error) {
+	vars, err := r.Value()
+	if err != nil {
+		return def, err
+	}
+	if v, ok := vars[r.env.Name(key)]; ok && v.quoted {
+		return v.value, nil
+	}
+	return r.env.GetDef(key, def)
+}
+
+// ParseDotenv reads NAME=value lines. Blank lines and lines starting with # are skipped, and "export " is allowed
+// before the name. Values can be in single quotes, taken literally, or double quotes, where \n, \", and \\ are escapes.
+// Unquoted values end at " #", which starts a comment.
+func ParseDotenv(content []byte) (map[string]string, error) {
+	vars, err := parseDotenv(content)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(vars))
+	for name, v := range vars {
+		values[name] = v.value
+	}
+	return values, nil
+}
+
+func parseDotenv(content []byte) (map[string]dotenvVar, error) {
+	vars := map[string]dotenvVar{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNr := 1; scanner.Scan(); lineNr++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("line %d is not NAME=value", lineNr)
+		}
+		value = strings.TrimSpace(value)
+		quoted := strings.HasPrefix(value, "'") || strings.HasPrefix(value, `"`)
+		value, err := dotenvValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNr, err)
+		}
+		vars[name] = dotenvVar{value: value, quoted: quoted}
+	}
+	return vars, scanner.Err()
+}
+
+func dotenvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "'"):
+		end := strings.Index(value[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quote in %s", value)
+		}
+		return value[1 : end+1], nil
+	case strings.HasPrefix(value, `"`):
+		var b strings.Builder
+		for i := 1; i < len(value); i++ {
+			switch c := value[i]; {
+			case c == '"':
+				return b.String(), nil
+			case c == '\\' && i+1 < len(value):
+				i++
+				switch value[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(value[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated quote in %s", value)
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		return value, nil
+	}
+}
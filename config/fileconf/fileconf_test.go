@@ -0,0 +1,174 @@
+package fileconf
+
+import (
+	"github.com/Direct-Debit/go-commons/config"
+	"github.com/Direct-Debit/go-commons/config/tomlold"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testYAML = `
+log_level: INFO
+rate: 2.5
+db:
+  host: db.local
+  port: 5432
+banks:
+  - absa
+  - fnb
+`
+
+const testJSON = `{
+  "log_level": "INFO",
+  "rate": 2.5,
+  "db": {"host": "db.local", "port": 5432},
+  "banks": ["absa", "fnb"]
+}`
+
+const testDotenv = `
+# comment
+LOG_LEVEL=INFO
+RATE=2.5
+export DB_HOST="db.local"
+DB_PORT=5432 # the default port
+BANKS=["absa", "fnb"]
+`
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestReadersAgree(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": testYAML,
+		"config.json": testJSON,
+		".env":        testDotenv,
+		"config.toml": "log_level = \"INFO\"\nrate = 2.5\nbanks = [\"absa\", \"fnb\"]\n[db]\nhost = \"db.local\"\nport = 5432\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			p, err := Open(writeFile(t, name, content))
+			assert.NoError(t, err)
+
+			v, err := p.Get("db.host")
+			assert.NoError(t, err)
+			assert.Equal(t, "db.local", v)
+			v, _ = p.Get("db.port")
+			assert.Equal(t, int64(5432), v)
+			v, _ = p.Get("rate")
+			assert.Equal(t, 2.5, v)
+			v, _ = p.Get("banks")
+			assert.Equal(t, []interface{}{"absa", "fnb"}, v)
+
+			_, err = p.Get("db.missing")
+			assert.Error(t, err)
+			v, err = p.GetDef("missing", "def")
+			assert.NoError(t, err)
+			assert.Equal(t, "def", v)
+		})
+	}
+}
+
+func TestOpenPicksProvider(t *testing.T) {
+	p, _ := Open("config.toml")
+	assert.IsType(t, &tomlold.Reader{}, p)
+	p, _ = Open("values.YML")
+	assert.IsType(t, &Reader{}, p)
+	p, _ = Open("/etc/app/.env.production")
+	assert.IsType(t, &DotenvReader{}, p)
+
+	_, err := Open("config.ini")
+	assert.Error(t, err)
+}
+
+func TestReaderMissingFile(t *testing.T) {
+	r := NewYAMLReader(filepath.Join(t.TempDir(), "missing.yaml"))
+	_, err := r.Get("a")
+	assert.Error(t, err)
+	_, err = r.GetDef("a", 1)
+	assert.Error(t, err)
+}
+
+func TestReaderReload(t *testing.T) {
+	path := writeFile(t, "config.json", `{"limit": 1}`)
+	r := NewJSONReader(path)
+	assert.False(t, r.Changed())
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"limit": 20}`), 0644))
+	assert.True(t, r.Changed())
+	assert.NoError(t, r.Reload())
+	v, _ := r.Get("limit")
+	assert.Equal(t, int64(20), v)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"limit": `), 0644))
+	assert.Error(t, r.Reload())
+	v, _ = r.Get("limit")
+	assert.Equal(t, int64(20), v, "values are kept when the file is broken")
+	assert.False(t, r.Changed(), "a broken file is not reloaded again until it changes")
+}
+
+func TestJSONNeedsObject(t *testing.T) {
+	r := NewJSONReader(writeFile(t, "config.json", `[1, 2]`))
+	_, err := r.Get("a")
+	assert.Error(t, err)
+}
+
+func TestParseDotenv(t *testing.T) {
+	vars, err := ParseDotenv([]byte(`
+A=plain value # comment
+B='single # not a comment'
+C="line\nbreak \"quoted\""
+export D=
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"A": "plain value",
+		"B": "single # not a comment",
+		"C": "line\nbreak \"quoted\"",
+		"D": "",
+	}, vars)
+
+	_, err = ParseDotenv([]byte("NOT A LINE"))
+	assert.Error(t, err)
+	_, err = ParseDotenv([]byte(`A="open`))
+	assert.Error(t, err)
+}
+
+func TestDotenvPrefix(t *testing.T) {
+	r := NewDotenvReader(writeFile(t, "app.env", "APP_DB_HOST=x\nDB_HOST=y"), "APP")
+	v, _ := r.Get("db.host")
+	assert.Equal(t, "x", v)
+}
+
+func TestDotenvQuotedStrings(t *testing.T) {
+	r := NewDotenvReader(writeFile(t, "app.env", "PIN=\"0123\"\nFLAG='true'\nCOUNT=12\nENABLED=true"), "")
+	v, _ := r.Get("pin")
+	assert.Equal(t, "0123", v)
+	v, _ = r.Get("flag")
+	assert.Equal(t, "true", v)
+	v, _ = r.Get("count")
+	assert.Equal(t, int64(12), v, "unquoted values are typed like environment variables")
+	v, _ = r.Get("enabled")
+	assert.Equal(t, true, v)
+}
+
+func TestBindYAML(t *testing.T) {
+	var c struct {
+		LogLevel string
+		DB       struct {
+			Host    string
+			Port    int
+			Timeout time.Duration `default:"5s"`
+		}
+		Banks []string
+	}
+	assert.NoError(t, config.BindFrom(NewYAMLReader(writeFile(t, "c.yaml", testYAML)), &c))
+	assert.Equal(t, 5432, c.DB.Port)
+	assert.Equal(t, 5*time.Second, c.DB.Timeout)
+	assert.Equal(t, []string{"absa", "fnb"}, c.Banks)
+}
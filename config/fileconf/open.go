@@ -0,0 +1,28 @@
+package fileconf
+
+import (
+	"fmt"
+	"github.com/Direct-Debit/go-commons/config"
+	"github.com/Direct-Debit/go-commons/config/tomlold"
+	"path/filepath"
+	"strings"
+)
+
+// Open returns the provider for a config file from its extension: .toml, .yaml or .yml, .json, or .env.
+// Files named like ".env.production" are dotenv files too. As with tomlold.Reader, an error loading the file is
+// returned by Get, so check Open's provider with Reload or config.Bind at startup.
+func Open(path string) (config.Provider, error) {
+	name := strings.ToLower(filepath.Base(path))
+	switch ext := filepath.Ext(name); {
+	case ext == ".toml":
+		return tomlold.NewReaderWithPath(path), nil
+	case ext == ".yaml" || ext == ".yml":
+		return NewYAMLReader(path), nil
+	case ext == ".json":
+		return NewJSONReader(path), nil
+	case ext == ".env" || strings.HasPrefix(name, ".env."):
+		return NewDotenvReader(path, ""), nil
+	default:
+		return nil, fmt.Errorf("no config provider for %s files", ext)
+	}
+}
@@ -0,0 +1,85 @@
+// Package fileconf has config.Provider implementations for YAML, JSON and dotenv files,
+// and Open, which picks the provider for a file from its extension.
+package fileconf
+
+import (
+	"fmt"
+	"github.com/Direct-Debit/go-commons/config/internal/filestate"
+	"strings"
+)
+
+// Reader is a config.Provider for a YAML or JSON file. Keys are dotted paths into nested tables, like "db.host",
+// the same as for tomlold.Reader. Numbers are returned as int64 or float64, lists as []interface{},
+// and tables as map[string]interface{}, so the config.Get helpers work the same for every file type.
+type Reader struct {
+	*filestate.File[map[string]interface{}]
+}
+
+// NewYAMLReader creates a Reader for a YAML file.
+func NewYAMLReader(path string) *Reader {
+	return &Reader{filestate.New(path, decodeYAML)}
+}
+
+// NewJSONReader creates a Reader for a JSON file, which must contain an object.
+func NewJSONReader(path string) *Reader {
+	return &Reader{filestate.New(path, decodeJSON)}
+}
+
+func (r *Reader) Get(key string) (interface{}, error) {
+	values, err := r.Value()
+	if err != nil {
+		return nil, err
+	}
+
+	v := lookup(values, key)
+	if v == nil {
+		return nil, fmt.Errorf("no config value for %s", key)
+	}
+	return v, nil
+}
+
+func (r *Reader) GetDef(key string, def interface{}) (interface{}, error) {
+	values, err := r.Value()
+	if err != nil {
+		return def, err
+	}
+
+	v := lookup(values, key)
+	if v == nil {
+		return def, nil
+	}
+	return v, nil
+}
+
+// Keys returns the dotted keys of all the values in the file, but not of the tables that contain them.
+func (r *Reader) Keys() []string {
+	values, _ := r.Value()
+	return mapKeys(values, "")
+}
+
+func mapKeys(values map[string]interface{}, prefix string) []string {
+	var keys []string
+	for k, v := range values {
+		if table, ok := v.(map[string]interface{}); ok {
+			keys = append(keys, mapKeys(table, prefix+k+".")...)
+			continue
+		}
+		keys = append(keys, prefix+k)
+	}
+	return keys
+}
+
+// lookup follows a dotted key through nested tables.
+func lookup(values map[string]interface{}, key string) interface{} {
+	var current interface{} = values
+	for _, part := range strings.Split(key, ".") {
+		table, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if current, ok = table[part]; !ok {
+			return nil
+		}
+	}
+	return current
+}
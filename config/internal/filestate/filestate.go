@@ -0,0 +1,85 @@
+// Package filestate loads config files for the file readers in config/tomlold and config/fileconf,
+// so they reload files and detect changes the same way.
+package filestate
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// File holds the parsed content of a config file. Readers embed it for its Path, Reload and Changed methods.
+type File[T any] struct {
+	path    string
+	parse   func(content []byte) (T, error)
+	lock    sync.RWMutex
+	value   T
+	loaded  bool
+	loadErr error
+	modTime time.Time
+	size    int64
+}
+
+// New creates a File for path and loads it. An error is kept, and returned by Value until a Reload succeeds.
+func New[T any](path string, parse func(content []byte) (T, error)) *File[T] {
+	f := &File[T]{path: path, parse: parse}
+	f.loadErr = f.load()
+	return f
+}
+
+// Path returns the path of the file.
+func (f *File[T]) Path() string {
+	return f.path
+}
+
+func (f *File[T]) load() error {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	// The file's state is recorded even if it doesn't parse, so Changed is false until it is modified again
+	f.modTime, f.size = info.ModTime(), info.Size()
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	value, err := f.parse(content)
+	if err != nil {
+		return fmt.Errorf("could not parse %s: %w", f.path, err)
+	}
+	f.value, f.loaded = value, true
+	return nil
+}
+
+// Reload reads the file again. If the file can't be read, the old content is kept, and the error is returned.
+func (f *File[T]) Reload() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	err := f.load()
+	if f.loaded {
+		f.loadErr = nil
+	} else {
+		f.loadErr = err
+	}
+	return err
+}
+
+// Changed reports whether the file was modified since it was last loaded, or since a load of it failed.
+func (f *File[T]) Changed() bool {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false
+	}
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return !info.ModTime().Equal(f.modTime) || info.Size() != f.size
+}
+
+// Value returns the parsed content, or the error of loading the file if it has never been loaded.
+func (f *File[T]) Value() (T, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.value, f.loadErr
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// Layer is a named Provider in a Layered provider. The name shows where a value came from, e.g. "env" or "dynamo".
+type Layer struct {
+	Name     string
+	Provider Provider
+}
+
+// Layered is a Provider that looks keys up in its layers in priority order, and returns the first value found.
+// A typical stack is environment variables, then a TOML file, then DynamoDB, then defaults:
+//
+//	dynamoConf, err := dynamo.NewConfig("config", "key", nil)
+//	errlib.WarnError(err, "Could not load the dynamo config table")
+//	config.SetProvider(config.NewLayered(
+//		config.Layer{Name: "env", Provider: config.NewEnv("APP")},
+//		config.Layer{Name: "file", Provider: tomlold.NewReaderWithPath("config.toml")},
+//		config.Layer{Name: "dynamo", Provider: dynamoConf},
+//		config.Layer{Name: "default", Provider: config.Defaults{"log_level": "INFO"}},
+//	))
+//
+// Values from an Env layer are converted to the type of the value in the layers below it, or of the default passed
+// to GetDef, so an environment variable can override an int64 in the TOML file and config.GetInt64 still works.
+// A layer that fails, like a TOML file that doesn't exist in a container, is skipped if another layer has the key,
+// but an environment variable that can't be converted is an error.
+type Layered struct {
+	layers []Layer
+}
+
+// NewLayered creates a Layered provider with the layers in priority order, highest first.
+func NewLayered(layers ...Layer) *Layered {
+	return &Layered{layers: layers}
+}
+
+// Layers returns the layers in priority order.
+func (l *Layered) Layers() []Layer {
+	return l.layers
+}
+
+// Reload reloads every layer, and returns the errors of all the layers that failed.
+func (l *Layered) Reload() error {
+	var failed []string
+	for _, layer := range l.layers {
+		if err := layer.Provider.Reload(); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", layer.Name, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("could not reload config layers: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Changed reports whether any layer changed. Layers that are not a ChangeDetector always count as changed.
+func (l *Layered) Changed() bool {
+	for _, layer := range l.layers {
+		if d, ok := layer.Provider.(ChangeDetector); !ok || d.Changed() {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Layered) Get(key string) (interface{}, error) {
+	v, _, err := l.lookup(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("no config value for %s in any layer", key)
+	}
+	return v, nil
+}
+
+func (l *Layered) GetDef(key string, def interface{}) (interface{}, error) {
+	v, _, err := l.lookup(key, def)
+	if v == nil {
+		return def, err
+	}
+	return v, nil
+}
+
+// lookup returns the value of key and the name of the layer it came from.
+// If no layer has the key, it returns nil and the first error from a layer.
+func (l *Layered) lookup(key string, def interface{}) (interface{}, string, error) {
+	var firstErr error
+	for i, layer := range l.layers {
+		var v interface{}
+		var err error
+		if env, ok := layer.Provider.(*Env); ok {
+			v, err = l.lookupEnv(env, i, key, def)
+			if err != nil {
+				// A variable that is set but wrong must not silently fall back to a lower layer
+				return nil, "", errors.Wrapf(err, "environment variable %s", env.Name(key))
+			}
+		} else {
+			v, err = layer.Provider.GetDef(key, nil)
+		}
+
+		if err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "config layer %s", layer.Name)
+			}
+			continue
+		}
+		if v != nil {
+			return v, layer.Name, nil
+		}
+	}
+	return nil, "", firstErr
+}
+
+// lookupEnv converts the environment variable for key to the type of the value in the layers below it, or def.
+func (l *Layered) lookupEnv(env *Env, index int, key string, def interface{}) (interface{}, error) {
+	s, ok := env.lookupString(key)
+	if !ok {
+		return nil, nil
+	}
+
+	like := def
+	for _, layer := range l.layers[index+1:] {
+		if _, isEnv := layer.Provider.(*Env); isEnv {
+			continue
+		}
+		if v, err := layer.Provider.GetDef(key, nil); err == nil && v != nil {
+			like = v
+			break
+		}
+	}
+	if like == nil {
+		return inferValue(s), nil
+	}
+	return convertLike(s, like)
+}
+
+// Defaults is a Provider for fixed values, keyed by their full dotted key, e.g. "db.port".
+type Defaults map[string]interface{}
+
+func (d Defaults) Reload() error {
+	return nil
+}
+
+func (d Defaults) Changed() bool {
+	return false
+}
+
+func (d Defaults) Get(key string) (interface{}, error) {
+	v, ok := d[key]
+	if !ok {
+		return nil, fmt.Errorf("no default value for %s", key)
+	}
+	return v, nil
+}
+
+func (d Defaults) GetDef(key string, def interface{}) (interface{}, error) {
+	v, ok := d[key]
+	if !ok {
+		return def, nil
+	}
+	return v, nil
+}
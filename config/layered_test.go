@@ -0,0 +1,105 @@
+package config
+
+import (
+	"github.com/Direct-Debit/go-commons/config/tomlold"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "APP_DB_HOST", NewEnv("APP").Name("db.host"))
+	assert.Equal(t, "APP_LOG_LEVEL", NewEnv("APP_").Name("log-level"))
+	assert.Equal(t, "DB_HOST", NewEnv("").Name("db.host"))
+}
+
+func TestEnvInfersTypes(t *testing.T) {
+	env := NewEnv("LAYERED_TEST")
+	t.Setenv("LAYERED_TEST_PORT", "5432")
+	t.Setenv("LAYERED_TEST_HOST", "db.local")
+	t.Setenv("LAYERED_TEST_DEBUG", "true")
+
+	v, err := env.Get("port")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5432), v)
+	v, _ = env.Get("host")
+	assert.Equal(t, "db.local", v)
+	v, _ = env.Get("debug")
+	assert.Equal(t, true, v)
+
+	_, err = env.Get("missing")
+	assert.Error(t, err)
+	v, err = env.GetDef("missing", "x")
+	assert.NoError(t, err)
+	assert.Equal(t, "x", v)
+}
+
+func TestLayeredPriority(t *testing.T) {
+	t.Setenv("LAYERED_TEST_TEST_INT", "9")
+	t.Setenv("LAYERED_TEST_DB_HOST", "env-host")
+	t.Setenv("LAYERED_TEST_DB_NAME", "0123")
+	t.Setenv("LAYERED_TEST_TEST_STR_LIST", "a, b,c")
+
+	l := NewLayered(
+		Layer{Name: "env", Provider: NewEnv("LAYERED_TEST")},
+		Layer{Name: "file", Provider: tomlold.NewReaderWithPath("config.toml")},
+		Layer{Name: "default", Provider: Defaults{"db.host": "localhost", "db.port": int64(5432), "db.name": "x"}},
+	)
+
+	v, err := l.Get("test_int")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), v, "env overrides the file, with the type of the file value")
+	v, _ = l.Get("db.host")
+	assert.Equal(t, "env-host", v)
+	v, _ = l.Get("db.name")
+	assert.Equal(t, "0123", v, "a string default keeps the env value a string")
+	v, _ = l.Get("db.port")
+	assert.Equal(t, int64(5432), v)
+	v, _ = l.Get("test_str_list")
+	assert.Equal(t, []interface{}{"a", "b", "c"}, v)
+
+	_, err = l.Get("missing")
+	assert.Error(t, err)
+	v, err = l.GetDef("missing", "def")
+	assert.NoError(t, err)
+	assert.Equal(t, "def", v)
+}
+
+func TestLayeredWithHelpers(t *testing.T) {
+	old := GetProvider()
+	defer SetProvider(old)
+
+	t.Setenv("LAYERED_TEST_RETRIES", "3")
+	SetProvider(NewLayered(
+		Layer{Name: "env", Provider: NewEnv("LAYERED_TEST")},
+		Layer{Name: "file", Provider: tomlold.NewReaderWithPath("config.toml")},
+	))
+
+	assert.Equal(t, 7, GetInt("test_int"))
+	assert.Equal(t, 3, GetIntDef("retries", 1), "env values get the type of the default")
+	assert.Equal(t, []string{"element1", "element2"}, GetStrList("test_str_list"))
+}
+
+func TestLayeredSkipsFailedLayers(t *testing.T) {
+	l := NewLayered(
+		Layer{Name: "file", Provider: tomlold.NewReaderWithPath("does-not-exist.toml")},
+		Layer{Name: "default", Provider: Defaults{"a": "b"}},
+	)
+	v, err := l.Get("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", v)
+
+	_, err = l.Get("c")
+	assert.Error(t, err)
+}
+
+func TestLayeredBadEnvValue(t *testing.T) {
+	t.Setenv("LAYERED_TEST_TEST_INT", "seven")
+	l := NewLayered(
+		Layer{Name: "env", Provider: NewEnv("LAYERED_TEST")},
+		Layer{Name: "file", Provider: tomlold.NewReaderWithPath("config.toml")},
+	)
+	_, err := l.Get("test_int")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "LAYERED_TEST_TEST_INT")
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"github.com/pkg/errors"
+	"sync"
+)
+
+// SecretResolver resolves secret references in config values, like "ssm:/prod/sftp/password".
+// Resolve returns ok false for values that are not references, which are used as they are.
+type SecretResolver interface {
+	Resolve(value string) (secret string, ok bool, err error)
+}
+
+// Secrets is a Provider that resolves secret references in the string values of another provider,
+// so config files can hold references instead of passwords:
+//
+//	config.SetProvider(config.NewSecrets(tomlold.NewReader(), secrets.NewResolver()))
+//	password := config.GetStr("sftp.password") // sftp.password = "ssm:/prod/sftp/password"
+//
+// If the resolver also has a Reload method, like secrets.Resolver, Reload calls it to drop cached secrets.
+type Secrets struct {
+	provider Provider
+	resolver SecretResolver
+
+	lock    sync.Mutex
+	secrets map[string]bool
+}
+
+// NewSecrets creates a Secrets provider that reads values from p and resolves references with r.
+func NewSecrets(p Provider, r SecretResolver) *Secrets {
+	return &Secrets{provider: p, resolver: r, secrets: map[string]bool{}}
+}
+
+// IsSecret reports whether the value of key was resolved from a secret reference when it was last read.
+func (s *Secrets) IsSecret(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.secrets[key]
+}
+
+func (s *Secrets) Reload() error {
+	err := s.provider.Reload()
+	if r, ok := s.resolver.(interface{ Reload() error }); ok {
+		if rErr := r.Reload(); err == nil {
+			err = rErr
+		}
+	}
+	return err
+}
+
+// Changed reports whether the wrapped provider changed, or true if it can't tell.
+func (s *Secrets) Changed() bool {
+	d, ok := s.provider.(ChangeDetector)
+	return !ok || d.Changed()
+}
+
+func (s *Secrets) Get(key string) (interface{}, error) {
+	v, err := s.provider.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolve(key, v)
+}
+
+func (s *Secrets) GetDef(key string, def interface{}) (interface{}, error) {
+	v, err := s.provider.GetDef(key, def)
+	if err != nil {
+		return v, err
+	}
+	return s.resolve(key, v)
+}
+
+// resolve resolves a string value, or the strings in a list.
+func (s *Secrets) resolve(key string, v interface{}) (interface{}, error) {
+	isSecret := false
+	resolveOne := func(value interface{}) (interface{}, error) {
+		str, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+		secret, ok, err := s.resolver.Resolve(str)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not resolve the secret for %s", key)
+		}
+		if !ok {
+			return value, nil
+		}
+		isSecret = true
+		return secret, nil
+	}
+
+	var result interface{}
+	var err error
+	if list, ok := v.([]interface{}); ok {
+		resolved := make([]interface{}, len(list))
+		for i, item := range list {
+			if resolved[i], err = resolveOne(item); err != nil {
+				return nil, err
+			}
+		}
+		result = resolved
+	} else if result, err = resolveOne(v); err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	s.secrets[key] = isSecret
+	s.lock.Unlock()
+	return result, nil
+}
@@ -3,36 +3,50 @@ package tomlold
 import (
 	"fmt"
 
+	"github.com/Direct-Debit/go-commons/config/internal/filestate"
 	"github.com/pelletier/go-toml"
 )
 
 type Reader struct {
-	conf    *toml.Tree
-	loadErr error
+	*filestate.File[*toml.Tree]
 }
 
 func NewReader() *Reader {
-	conf, err := toml.LoadFile("config.toml")
-	return &Reader{conf: conf, loadErr: err}
+	return NewReaderWithPath("config.toml")
 }
 
 func NewReaderWithPath(path string) *Reader {
-	conf, err := toml.LoadFile(path)
-	return &Reader{conf: conf, loadErr: err}
+	return &Reader{filestate.New(path, toml.LoadBytes)}
 }
 
-func (r *Reader) Reload() error {
-	var err error
-	r.conf, err = toml.LoadFile("config.toml")
-	return err
+// Keys returns the dotted keys of all the values in the file, but not of the tables that contain them.
+func (r *Reader) Keys() []string {
+	conf, err := r.Value()
+	if err != nil {
+		return nil
+	}
+	return treeKeys(conf, "")
+}
+
+func treeKeys(tree *toml.Tree, prefix string) []string {
+	var keys []string
+	for _, k := range tree.Keys() {
+		if sub, ok := tree.GetPath([]string{k}).(*toml.Tree); ok {
+			keys = append(keys, treeKeys(sub, prefix+k+".")...)
+			continue
+		}
+		keys = append(keys, prefix+k)
+	}
+	return keys
 }
 
 func (r *Reader) Get(key string) (interface{}, error) {
-	if r.loadErr != nil {
-		return nil, r.loadErr
+	conf, err := r.Value()
+	if err != nil {
+		return nil, err
 	}
 
-	v := r.conf.Get(key)
+	v := conf.Get(key)
 	if v == nil {
 		return nil, fmt.Errorf("no config value for %s", key)
 	}
@@ -40,11 +54,12 @@ func (r *Reader) Get(key string) (interface{}, error) {
 }
 
 func (r *Reader) GetDef(key string, def interface{}) (interface{}, error) {
-	if r.loadErr != nil {
-		return def, r.loadErr
+	conf, err := r.Value()
+	if err != nil {
+		return def, err
 	}
 
-	val := r.conf.Get(key)
+	val := conf.Get(key)
 	if val == nil {
 		return def, nil
 	}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"github.com/Direct-Debit/go-commons/errlib"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ChangeDetector is implemented by providers that can tell cheaply whether their source changed,
+// like a TOML file's modification time. Watch only reloads them when Changed is true.
+// Providers that don't implement it, like DynamoDB, are reloaded on every poll.
+type ChangeDetector interface {
+	Changed() bool
+}
+
+type subscription struct {
+	id   int
+	key  string
+	fn   func(value interface{})
+	last interface{}
+}
+
+var (
+	subscriptionsLock sync.Mutex
+	subscriptions     []*subscription
+	nextSubscription  int
+)
+
+// OnChange calls fn with the new value of key, or nil if it was removed, each time a reload changes it.
+// Call the returned function to stop the notifications. Use it with Watch to react to changes without restarting:
+//
+//	config.OnChange("log_level", func(interface{}) { log.SetLevel(config.GetLogLevel()) })
+//	stop := config.Watch(30 * time.Second)
+//	defer stop()
+func OnChange(key string, fn func(value interface{})) (cancel func()) {
+	current, _ := GetProvider().GetDef(key, nil)
+
+	subscriptionsLock.Lock()
+	defer subscriptionsLock.Unlock()
+	nextSubscription++
+	id := nextSubscription
+	subscriptions = append(subscriptions, &subscription{id: id, key: key, fn: fn, last: current})
+
+	return func() {
+		subscriptionsLock.Lock()
+		defer subscriptionsLock.Unlock()
+		for i, s := range subscriptions {
+			if s.id == id {
+				subscriptions = append(subscriptions[:i], subscriptions[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// notifyChanges calls the OnChange functions of the keys whose values differ from the last reload.
+func notifyChanges(p Provider) {
+	type change struct {
+		fn    func(value interface{})
+		value interface{}
+	}
+
+	// The provider is read without holding the lock, so a slow provider or an OnChange call doesn't wait on it
+	subscriptionsLock.Lock()
+	subs := append([]*subscription(nil), subscriptions...)
+	subscriptionsLock.Unlock()
+
+	values := map[string]interface{}{}
+	read := map[string]bool{}
+	for _, s := range subs {
+		if read[s.key] {
+			continue
+		}
+		read[s.key] = true
+		v, err := p.GetDef(s.key, nil)
+		if errlib.WarnError(err, "Could not read %s after config reload", s.key) {
+			continue
+		}
+		values[s.key] = v
+	}
+
+	subscriptionsLock.Lock()
+	var changes []change
+	for _, s := range subs {
+		v, ok := values[s.key]
+		if ok && !reflect.DeepEqual(v, s.last) {
+			s.last = v
+			changes = append(changes, change{fn: s.fn, value: v})
+		}
+	}
+	subscriptionsLock.Unlock()
+
+	for _, c := range changes {
+		c.fn(c.value)
+	}
+}
+
+// Watch reloads the config every interval, if it changed, until the returned function is called.
+// Reload errors are logged, and the provider keeps serving the values it has.
+func Watch(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if d, ok := GetProvider().(ChangeDetector); ok && !d.Changed() {
+					continue
+				}
+				errlib.WarnError(Reload(), "Could not reload config")
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
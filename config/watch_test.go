@@ -0,0 +1,95 @@
+package config
+
+import (
+	"github.com/Direct-Debit/go-commons/config/tomlold"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestToml(t *testing.T, path, content string) {
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+// testToml writes content to a TOML file in a temporary directory, and returns a reader for it and its path.
+func testToml(t *testing.T, content string) (*tomlold.Reader, string) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeTestToml(t, path, content)
+	return tomlold.NewReaderWithPath(path), path
+}
+
+// useTestToml is testToml, with the reader as the Provider until the test ends.
+func useTestToml(t *testing.T, content string) (path string) {
+	r, path := testToml(t, content)
+	old := GetProvider()
+	SetProvider(r)
+	t.Cleanup(func() { SetProvider(old) })
+	return path
+}
+
+func TestReloadUsesReaderPath(t *testing.T) {
+	path := useTestToml(t, `test_int = 1`)
+	writeTestToml(t, path, `test_int = 2`)
+	assert.NoError(t, Reload())
+	assert.Equal(t, 2, GetInt("test_int"), "the reader's own file is reloaded, not config.toml")
+}
+
+func TestReloadKeepsValuesOnError(t *testing.T) {
+	path := useTestToml(t, `test_int = 1`)
+	writeTestToml(t, path, `test_int = `)
+	assert.Error(t, Reload())
+	assert.Equal(t, 1, GetInt("test_int"))
+	assert.False(t, GetProvider().(ChangeDetector).Changed(), "a broken file is not reloaded again until it changes")
+}
+
+func TestOnChange(t *testing.T) {
+	path := useTestToml(t, "log_level = \"INFO\"\nrate = 5")
+
+	var levels []interface{}
+	cancel := OnChange("log_level", func(v interface{}) { levels = append(levels, v) })
+	rateChanges := 0
+	defer OnChange("rate", func(interface{}) { rateChanges++ })()
+
+	writeTestToml(t, path, "log_level = \"DEBUG\"\nrate = 5")
+	assert.NoError(t, Reload())
+	writeTestToml(t, path, "rate = 5")
+	assert.NoError(t, Reload())
+	assert.Equal(t, []interface{}{"DEBUG", nil}, levels)
+	assert.Equal(t, 0, rateChanges)
+
+	cancel()
+	writeTestToml(t, path, "log_level = \"WARNING\"\nrate = 6")
+	assert.NoError(t, Reload())
+	assert.Len(t, levels, 2)
+	assert.Equal(t, 1, rateChanges)
+}
+
+func TestWatch(t *testing.T) {
+	path := useTestToml(t, `limit = 10`)
+	changes := make(chan interface{}, 1)
+	defer OnChange("limit", func(v interface{}) { changes <- v })()
+
+	stop := Watch(10 * time.Millisecond)
+	defer stop()
+	writeTestToml(t, path, `limit = 200`)
+
+	select {
+	case v := <-changes:
+		assert.Equal(t, int64(200), v)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch did not reload the changed file")
+	}
+}
+
+func TestLayeredChanged(t *testing.T) {
+	file, path := testToml(t, `a = 1`)
+
+	l := NewLayered(Layer{Name: "env", Provider: NewEnv("X")}, Layer{Name: "file", Provider: file})
+	assert.False(t, l.Changed())
+	writeTestToml(t, path, `a = 12`)
+	assert.True(t, l.Changed())
+	assert.NoError(t, l.Reload())
+	assert.False(t, l.Changed())
+}
@@ -0,0 +1,150 @@
+package filespec
+
+import (
+	"fmt"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Names of the built-in character sets for the charset tag.
+// Values are transliterated and, for alphanumeric fields, upper-cased before they are checked.
+const (
+	CharsetNumeric      = "numeric"      // 0-9
+	CharsetAlpha        = "alpha"        // A-Z and space
+	CharsetAlphanumeric = "alphanumeric" // A-Z, 0-9 and space
+	CharsetBank         = "bank"         // A-Z, 0-9, space and . , - / & ( ) ' + : ?
+	CharsetASCII        = "ascii"        // Printable ASCII
+)
+
+// Values for the overflow tag.
+// Alphanumeric fields are truncated by default, all other fields return an error.
+const (
+	OverflowTruncate = "truncate"
+	OverflowError    = "error"
+)
+
+const (
+	digits  = "0123456789"
+	letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+)
+
+var (
+	charsets = map[string]string{
+		CharsetNumeric:      digits,
+		CharsetAlpha:        letters + " ",
+		CharsetAlphanumeric: letters + digits + " ",
+		CharsetBank:         letters + digits + " .,-/&()'+:?",
+		CharsetASCII:        printableASCII(),
+	}
+	charsetsLock sync.RWMutex
+)
+
+func printableASCII() string {
+	var b strings.Builder
+	for c := ' '; c <= '~'; c++ {
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// RegisterCharset makes a custom set of allowed characters available to the charset tag.
+// Register custom charsets before the record types using them are first used.
+func RegisterCharset(name string, allowed string) {
+	charsetsLock.Lock()
+	defer charsetsLock.Unlock()
+	charsets[name] = allowed
+}
+
+func lookupCharset(name string) (string, bool) {
+	charsetsLock.RLock()
+	defer charsetsLock.RUnlock()
+	allowed, ok := charsets[name]
+	return allowed, ok
+}
+
+var transliterations = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE",
+	"ø", "o", "Ø", "O", "đ", "d", "Đ", "D", "ł", "l", "Ł", "L",
+	"‘", "'", "’", "'", "“", "\"", "”", "\"", "–", "-", "—", "-", "\u00a0", " ",
+)
+
+// Transliterate replaces accented letters and typographic punctuation with their closest ASCII equivalents,
+// e.g. "Müller–Ørsted" becomes "Muller-Orsted". Characters without an equivalent are left as is.
+func Transliterate(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(t, transliterations.Replace(s))
+	if err != nil {
+		return s
+	}
+	return result
+}
+
+// sanitize transliterates and upper-cases alphanumeric values, and checks the value against the field's charset.
+func (fc fieldCodec) sanitize(value string) (string, error) {
+	if fc.tag.Type == "A" || fc.tag.Type == "AN" {
+		value = strings.ToUpper(Transliterate(value))
+	}
+	if fc.allowed == "" {
+		return value, nil
+	}
+	for _, c := range value {
+		if !strings.ContainsRune(fc.allowed, c) {
+			return "", fmt.Errorf("%q in %s is not in the %s charset", c, fc.name, fc.tag.Charset)
+		}
+	}
+	return value, nil
+}
+
+// fit makes sure the padded value is no longer than the field, truncating it if the field allows it.
+func (fc fieldCodec) fit(value string) (string, error) {
+	length := fc.tag.Length()
+	r := []rune(value)
+	if len(r) <= length {
+		return value, nil
+	}
+
+	overflow := fc.tag.Overflow
+	if overflow == "" && (fc.tag.Type == "A" || fc.tag.Type == "AN") {
+		overflow = OverflowTruncate
+	}
+	if overflow == OverflowTruncate {
+		return string(r[:length]), nil
+	}
+	return "", fmt.Errorf("%s is %d characters long, but %s only has %d", value, len(r), fc.name, length)
+}
+
+// Encoding converts generated files to, and received files from, the character encoding a bank uses.
+// Content stays in strings, so it can be passed to a fileio.FileStore as is.
+type Encoding struct {
+	Name    string
+	charmap *charmap.Charmap
+}
+
+var (
+	Latin1 = Encoding{Name: "ISO-8859-1", charmap: charmap.ISO8859_1}
+	EBCDIC = Encoding{Name: "IBM037", charmap: charmap.CodePage037}
+)
+
+// Encode converts UTF-8 content to the encoding.
+// Characters that the encoding can't represent result in an error, rather than being replaced.
+func (e Encoding) Encode(content string) (string, error) {
+	result, err := e.charmap.NewEncoder().String(content)
+	if err != nil {
+		return "", fmt.Errorf("could not encode content as %s: %w", e.Name, err)
+	}
+	return result, nil
+}
+
+// Decode converts content in the encoding to UTF-8.
+func (e Encoding) Decode(content string) (string, error) {
+	result, err := e.charmap.NewDecoder().String(content)
+	if err != nil {
+		return "", fmt.Errorf("could not decode %s content: %w", e.Name, err)
+	}
+	return result, nil
+}
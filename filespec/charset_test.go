@@ -0,0 +1,79 @@
+package filespec
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	assert.Equal(t, "Muller-Orsted", Transliterate("Müller–Ørsted"))
+	assert.Equal(t, "Jose Francois Strasse", Transliterate("José François Straße"))
+	assert.Equal(t, "O'Brien", Transliterate("O’Brien"))
+	assert.Equal(t, "日本", Transliterate("日本"))
+}
+
+func TestGenerateLineCharset(t *testing.T) {
+	type record struct {
+		Name    string `pos:"1-8" type:"A" charset:"bank"`
+		Ref     string `pos:"9-12" type:"AN" overflow:"error"`
+		Account string `pos:"13-16" type:"N" charset:"numeric"`
+	}
+
+	var b strings.Builder
+	assert.NoError(t, GenerateLine(record{"Zoë Müller", "ab", "12"}, &b))
+	assert.Equal(t, "ZOE MULLAB  0012\n", b.String())
+
+	assert.Error(t, GenerateLine(record{"Zoë_M", "ab", "12"}, &b))
+	assert.Error(t, GenerateLine(record{"Zoe", "abcde", "12"}, &b))
+	assert.Error(t, GenerateLine(record{"Zoe", "ab", "12345"}, &b))
+
+	assert.Error(t, Register(struct {
+		A string `pos:"1-2" charset:"klingon"`
+	}{}))
+	assert.Error(t, Register(struct {
+		A string `pos:"1-2" overflow:"wrap"`
+	}{}))
+
+	RegisterCharset("hex", "0123456789ABCDEF")
+	type hexRecord struct {
+		Hash string `pos:"1-4" type:"AN" charset:"hex"`
+	}
+	b.Reset()
+	assert.NoError(t, GenerateLine(hexRecord{"beef"}, &b))
+	assert.Equal(t, "BEEF\n", b.String())
+	assert.Error(t, GenerateLine(hexRecord{"beer"}, &b))
+}
+
+func TestMultiByteRoundTrip(t *testing.T) {
+	type record struct {
+		Name string `pos:"1-5" type:"A"`
+		Amt  int    `pos:"6-10" type:"N"`
+	}
+
+	var b strings.Builder
+	assert.NoError(t, GenerateLine(record{"€A", 12}, &b))
+	assert.Equal(t, "€A   00012\n", b.String())
+
+	var parsed record
+	assert.NoError(t, ParseRecord(strings.TrimSuffix(b.String(), "\n"), &parsed))
+	assert.Equal(t, 12, parsed.Amt, "fields after a multi-byte rune keep their position")
+	assert.Equal(t, "€A", strings.TrimSpace(parsed.Name))
+}
+
+func TestEncoding(t *testing.T) {
+	ebcdic, err := EBCDIC.Encode("AB12 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "\xc1\xc2\xf1\xf2\x40", ebcdic)
+
+	decoded, err := EBCDIC.Decode(ebcdic)
+	assert.NoError(t, err)
+	assert.Equal(t, "AB12 ", decoded)
+
+	latin1, err := Latin1.Encode("José")
+	assert.NoError(t, err)
+	assert.Equal(t, "Jos\xe9", latin1)
+
+	_, err = Latin1.Encode("日本")
+	assert.Error(t, err)
+}
@@ -0,0 +1,459 @@
+package filespec
+
+import (
+	"encoding"
+	"fmt"
+	"github.com/Direct-Debit/go-commons/format"
+	"github.com/Direct-Debit/go-commons/stdext"
+	"github.com/pkg/errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// codecs caches a *recordCodec for every record type that has been used, keyed by reflect.Type.
+var codecs sync.Map
+
+type parseFunc func(field reflect.Value, strVal string, tag RecordTag) error
+
+type toStrFunc func(val reflect.Value, tag RecordTag) (string, error)
+
+// fieldCodec reads and writes a single fixed-width field.
+// The parse and toStr functions are picked once for the field's type, so no type switching happens per line.
+type fieldCodec struct {
+	name    string
+	index   int
+	tag     RecordTag
+	parse   parseFunc
+	toStr   toStrFunc
+	padded  bool   // toStr already returns the full-width value, so pad is skipped
+	allowed string // Characters allowed by the charset tag, empty if any character is allowed
+}
+
+// recordCodec is the compiled layout of a record struct.
+type recordCodec struct {
+	fields []fieldCodec
+	width  int
+}
+
+// Register compiles the layout of the given record struct (or pointer to one) and caches it.
+// Invalid tags, overlapping or out-of-order positions and unsupported field types are all reported here,
+// so calling Register at startup catches layout mistakes before any file is processed.
+// ParseRecord and GenerateLine register types implicitly, so calling Register is optional.
+func Register(record interface{}) error {
+	t := reflect.TypeOf(record)
+	if t == nil {
+		return errors.New("cannot register nil record")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	_, err := codecFor(t)
+	return err
+}
+
+func codecFor(t reflect.Type) (*recordCodec, error) {
+	if c, ok := codecs.Load(t); ok {
+		return c.(*recordCodec), nil
+	}
+
+	c, err := compileRecord(t)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := codecs.LoadOrStore(t, c)
+	return actual.(*recordCodec), nil
+}
+
+func compileRecord(t reflect.Type) (*recordCodec, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("record type %v is not a struct", t)
+	}
+
+	c := &recordCodec{fields: make([]fieldCodec, 0, t.NumField())}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, err := ParseRecordTag(sf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid tag on %s", sf.Name)
+		}
+		if err := c.checkPosition(sf.Name, tag); err != nil {
+			return nil, err
+		}
+
+		fc, err := compileField(sf.Name, sf.Type, tag)
+		if err != nil {
+			return nil, err
+		}
+		fc.index = i
+		c.fields = append(c.fields, fc)
+		c.width = tag.End
+	}
+	return c, nil
+}
+
+// checkPosition makes sure the field starts after the previous field ends.
+func (c *recordCodec) checkPosition(name string, tag RecordTag) error {
+	if tag.Start < 1 || tag.End < tag.Start {
+		return fmt.Errorf("invalid position %d-%d on %s", tag.Start, tag.End, name)
+	}
+	if len(c.fields) == 0 {
+		return nil
+	}
+
+	prev := c.fields[len(c.fields)-1]
+	if tag.Start <= prev.tag.End {
+		return fmt.Errorf(
+			"%s at %d-%d overlaps or is out of order with %s at %d-%d",
+			name, tag.Start, tag.End, prev.name, prev.tag.Start, prev.tag.End,
+		)
+	}
+	return nil
+}
+
+func compileField(name string, t reflect.Type, tag RecordTag) (fieldCodec, error) {
+	fc := fieldCodec{name: name, tag: tag}
+	ptrType := reflect.PtrTo(t)
+
+	if tag.Charset != "" {
+		allowed, ok := lookupCharset(tag.Charset)
+		if !ok {
+			return fc, fmt.Errorf("unknown charset %s on %s", tag.Charset, name)
+		}
+		fc.allowed = allowed
+	}
+
+	switch {
+	case t == timeType:
+		if tag.Format == "" && !defaultLengths[tag.Length()] {
+			return fc, fmt.Errorf("date field %s of length %d needs a format tag", name, tag.Length())
+		}
+		fc.parse, fc.toStr = parseTime, timeToStr
+		return fc, nil
+	case ptrType.Implements(fixedWidthUnmarshalerType):
+		fc.parse = parseFixedWidth
+	case ptrType.Implements(textUnmarshalerType):
+		fc.parse = parseText
+	}
+	switch {
+	case ptrType.Implements(fixedWidthMarshalerType):
+		fc.toStr = fixedWidthToStr
+	case ptrType.Implements(textMarshalerType):
+		fc.toStr = textToStr
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		fc.setDefaults(parseString, stringToStr)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if tag.numeric() {
+			fc.setNumeric(parseSignedNumber, signedNumberToStr)
+		} else {
+			fc.setDefaults(parseInt, intToStr)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		fc.setNumeric(parseUnsignedNumber, unsignedNumberToStr)
+	case reflect.Bool:
+		fc.setDefaults(parseBool, boolToStr)
+	case reflect.Float32, reflect.Float64:
+		if tag.Sign != "" || tag.Decimals > 0 {
+			fc.setNumeric(parseFloatNumber, floatNumberToStr)
+		} else {
+			fc.setNumeric(parseFloat, floatNumberToStr)
+		}
+	case reflect.Struct:
+		if fc.parse == nil || fc.toStr == nil {
+			return fc, fmt.Errorf("unsupported struct %v on %s", t, name)
+		}
+	}
+	return fc, nil
+}
+
+// setDefaults sets the parse and toStr functions, unless they have already been set by a custom codec.
+func (fc *fieldCodec) setDefaults(parse parseFunc, toStr toStrFunc) {
+	if fc.parse == nil {
+		fc.parse = parse
+	}
+	if fc.toStr == nil {
+		fc.toStr = toStr
+	}
+}
+
+// setNumeric is like setDefaults, but marks the field as padded if the numeric toStr is used.
+func (fc *fieldCodec) setNumeric(parse parseFunc, toStr toStrFunc) {
+	if fc.toStr == nil {
+		fc.padded = true
+	}
+	fc.setDefaults(parse, toStr)
+}
+
+func (c *recordCodec) parse(line string, target reflect.Value) error {
+	// Positions count characters, like generate does, so lines with multi-byte runes are sliced as runes
+	var runes []rune
+	length := len(line)
+	if !isASCII(line) {
+		runes = []rune(line)
+		length = len(runes)
+	}
+
+	for _, fc := range c.fields {
+		if fc.parse == nil {
+			continue
+		}
+
+		end := fc.tag.End
+		if length < end {
+			end = length
+			if end < fc.tag.Start-1 {
+				return fmt.Errorf("line too short: %s", line)
+			}
+		}
+
+		value := line[fc.tag.Start-1 : end]
+		if runes != nil {
+			value = string(runes[fc.tag.Start-1 : end])
+		}
+		err := fc.parse(target.Field(fc.index), value, fc.tag)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse %s", fc.name)
+		}
+	}
+	return nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *recordCodec) generate(source reflect.Value, builder *strings.Builder) error {
+	line := make([]rune, c.width+1) // +1 for newline character
+	for i := range line {
+		line[i] = ' '
+	}
+
+	for _, fc := range c.fields {
+		var value string
+		var err error
+		if fc.toStr != nil {
+			value, err = fc.toStr(source.Field(fc.index), fc.tag)
+			if err != nil {
+				return errors.Wrapf(err, "could not convert %s to string", fc.name)
+			}
+		}
+
+		value, err = fc.sanitize(value)
+		if err != nil {
+			return err
+		}
+		if !fc.padded {
+			value, err = fc.pad(value)
+			if err != nil {
+				return err
+			}
+		}
+		value, err = fc.fit(value)
+		if err != nil {
+			return err
+		}
+
+		for idx, r := range []rune(value) {
+			line[fc.tag.Start-1+idx] = r
+		}
+	}
+
+	line[len(line)-1] = '\n'
+	builder.WriteString(string(line))
+	return nil
+}
+
+// pad formats the value to the field's length according to its type tag.
+func (fc fieldCodec) pad(value string) (string, error) {
+	tag := fc.tag
+	switch tag.Type {
+	case "N":
+		value = fmt.Sprintf("%0*s", tag.Length(), value)
+		_, err := strconv.Atoi(value)
+		if err != nil && !strings.Contains(value, "TEST") {
+			return "", errors.Wrapf(err, "could not convert integer value from %s", fc.name)
+		}
+	case "C":
+		value = fmt.Sprintf("%0*s", tag.Length(), value)
+		_, err := strconv.ParseFloat(value, 64)
+		if err != nil && !strings.Contains(value, "TEST") {
+			return "", errors.Wrapf(err, "could not convert float value from %s", fc.name)
+		}
+	case "A", "AN":
+		switch tag.Format {
+		case "align-right":
+			value = fmt.Sprintf("%*s", tag.Length(), value)
+		default:
+			value = fmt.Sprintf("%-*s", tag.Length(), value) // Default to left align
+		}
+	}
+	return value, nil
+}
+
+func parseFixedWidth(field reflect.Value, strVal string, tag RecordTag) error {
+	return field.Addr().Interface().(FixedWidthUnmarshaler).UnmarshalFixedWidth(strVal, tag)
+}
+
+func parseText(field reflect.Value, strVal string, _ RecordTag) error {
+	return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(strings.TrimSpace(strVal)))
+}
+
+func parseString(field reflect.Value, strVal string, tag RecordTag) error {
+	if tag.Type == "N" {
+		strVal = strings.TrimSpace(strVal)
+		strVal = strings.TrimLeft(strVal, "0")
+	}
+	field.SetString(strVal)
+	return nil
+}
+
+func parseInt(field reflect.Value, strVal string, _ RecordTag) error {
+	intVal, err := strconv.ParseInt(strVal, 10, 64)
+	if err != nil {
+		return errors.Wrap(err, "could not parse int")
+	}
+	field.SetInt(intVal)
+	return nil
+}
+
+func parseBool(field reflect.Value, strVal string, tag RecordTag) error {
+	switch tag.Type {
+	case "N":
+		intVal, err := strconv.ParseInt(strVal, 10, 64)
+		if err != nil {
+			return errors.Wrap(err, "could not parse bool")
+		}
+		field.SetBool(intVal > 0)
+	case "AN", "A":
+		field.SetBool(true)
+		val := strings.ToLower(strings.TrimSpace(strVal))
+		for _, s := range []string{
+			"n", "no",
+			"f", "false",
+			"0",
+		} {
+			if s == val {
+				field.SetBool(false)
+			}
+		}
+	}
+	return nil
+}
+
+func parseFloat(field reflect.Value, strVal string, _ RecordTag) error {
+	floatVal, err := strconv.ParseFloat(strVal, field.Type().Bits())
+	if err != nil {
+		return errors.Wrap(err, "could not parse float")
+	}
+	field.SetFloat(floatVal)
+	return nil
+}
+
+func parseTime(field reflect.Value, strVal string, tag RecordTag) error {
+	if len(strings.TrimSpace(strVal)) == 0 {
+		field.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+
+	if tag.Format == "" && !defaultLengths[tag.Length()] {
+		return fmt.Errorf("invalid time length: %d", tag.Length())
+	}
+	timeVal, err := dateFormat(tag).Parse(strVal)
+	if err != nil {
+		return errors.Wrap(err, "could not parse time")
+	}
+	field.Set(reflect.ValueOf(timeVal))
+	return nil
+}
+
+// addressable returns val itself if it is addressable, otherwise a pointer to a copy of val.
+// This makes methods with pointer receivers callable on values taken from a record passed by value.
+func addressable(val reflect.Value) reflect.Value {
+	if val.CanAddr() {
+		return val.Addr()
+	}
+	ptr := reflect.New(val.Type())
+	ptr.Elem().Set(val)
+	return ptr
+}
+
+func fixedWidthToStr(val reflect.Value, tag RecordTag) (string, error) {
+	return addressable(val).Interface().(FixedWidthMarshaler).MarshalFixedWidth(tag)
+}
+
+func textToStr(val reflect.Value, _ RecordTag) (string, error) {
+	b, err := addressable(val).Interface().(encoding.TextMarshaler).MarshalText()
+	return string(b), err
+}
+
+func stringToStr(val reflect.Value, _ RecordTag) (string, error) {
+	return val.String(), nil
+}
+
+func intToStr(val reflect.Value, tag RecordTag) (string, error) {
+	switch tag.Type {
+	case "C":
+		fVal := stdext.RoundTo(float64(val.Int())/100, 2)
+		return fmt.Sprintf("%.2f", fVal), nil
+	default:
+		return strconv.FormatInt(val.Int(), 10), nil
+	}
+}
+
+func boolToStr(val reflect.Value, tag RecordTag) (string, error) {
+	switch tag.Type {
+	case "N":
+		if val.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case "A", "AN":
+		if val.Bool() {
+			return "Y", nil
+		}
+		return "N", nil
+	}
+	return "", nil
+}
+
+func timeToStr(val reflect.Value, tag RecordTag) (string, error) {
+	date := val.Interface().(time.Time)
+	return dateFormat(tag).Format(date), nil
+}
+
+// defaultLengths are the field lengths that have a default date layout.
+var defaultLengths = map[int]bool{4: true, 6: true, 8: true, 10: true}
+
+// dateFormat returns the format of a date field: the named format or layout in its format tag,
+// or a layout based on its length. The defaults are the same for parsing and generating.
+func dateFormat(tag RecordTag) format.DateFormat {
+	if tag.Format != "" {
+		return format.ResolveDateFormat(tag.Format)
+	}
+	switch tag.Length() {
+	case 10:
+		return format.DateFormat{Layouts: []string{format.DateShortSlashes}}
+	case 8:
+		return format.DateFormat{Layouts: []string{format.DateShort8}}
+	case 4:
+		return format.DateFormat{Layouts: []string{format.MMYY}}
+	default:
+		return format.DateFormat{Layouts: []string{format.DateShort6}}
+	}
+}
+
+// timeFormat returns the layout used to generate the date field.
+func timeFormat(tag RecordTag) string {
+	return dateFormat(tag).Layouts[0]
+}
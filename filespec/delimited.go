@@ -0,0 +1,256 @@
+package filespec
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/Direct-Debit/go-commons/format"
+	"github.com/pkg/errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Delimited describes a delimiter separated file, such as a CSV or pipe-delimited bank report.
+// Records are structs tagged like fixed-width records, but with a column instead of a position:
+//   - col: The 1-based column index. Defaults to the position of the field in the struct, "-" skips the field.
+//   - header: The name of the column in the header row, defaults to the field name. When parsing a file with a
+//     header row, fields are matched by name instead of by index, except fields with a col tag and no header tag.
+//
+// The type, format, sign, decimals and charset tags have the same meaning as for fixed-width records,
+// except that values are never padded or truncated. Dates default to format.DateShortDashes.
+// Values containing the delimiter, quotes or newlines are quoted as per RFC 4180.
+type Delimited struct {
+	Comma   rune // Field delimiter, defaults to ','
+	Header  bool // Whether the first row is a header row
+	UseCRLF bool // Whether to end generated lines with \r\n instead of \n
+}
+
+var delimitedCodecs sync.Map
+
+type delimitedColumn struct {
+	fieldCodec
+	column int // 0-based
+	header string
+	// byIndex is set for fields with a col tag and no header tag, which keep their column in files with headers.
+	byIndex bool
+}
+
+type delimitedCodec struct {
+	columns []delimitedColumn
+	width   int
+}
+
+func delimitedCodecFor(t reflect.Type) (*delimitedCodec, error) {
+	if c, ok := delimitedCodecs.Load(t); ok {
+		return c.(*delimitedCodec), nil
+	}
+
+	c, err := compileDelimited(t)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := delimitedCodecs.LoadOrStore(t, c)
+	return actual.(*delimitedCodec), nil
+}
+
+func compileDelimited(t reflect.Type) (*delimitedCodec, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("record type %v is not a struct", t)
+	}
+
+	c := &delimitedCodec{}
+	used := make(map[int]string, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		col := i + 1
+		if colTag := sf.Tag.Get("col"); colTag == "-" {
+			continue
+		} else if colTag != "" {
+			var err error
+			col, err = strconv.Atoi(colTag)
+			if err != nil || col < 1 {
+				return nil, fmt.Errorf("col tag invalid for %s", sf.Name)
+			}
+		}
+		if other, ok := used[col]; ok {
+			return nil, fmt.Errorf("%s and %s are both in column %d", other, sf.Name, col)
+		}
+		used[col] = sf.Name
+
+		tag, err := parseValueTags(sf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid tag on %s", sf.Name)
+		}
+		tag.Start, tag.End = 1, 0 // no fixed width
+		if sf.Type == timeType && tag.Format == "" {
+			tag.Format = format.DateShortDashes
+		}
+
+		fc, err := compileField(sf.Name, sf.Type, tag)
+		if err != nil {
+			return nil, err
+		}
+		fc.index = i
+
+		header := sf.Tag.Get("header")
+		byIndex := header == "" && sf.Tag.Get("col") != ""
+		if header == "" {
+			header = sf.Name
+		}
+		c.columns = append(c.columns, delimitedColumn{fieldCodec: fc, column: col - 1, header: header, byIndex: byIndex})
+		if col > c.width {
+			c.width = col
+		}
+	}
+	return c, nil
+}
+
+func (d Delimited) reader(content string) *csv.Reader {
+	r := csv.NewReader(strings.NewReader(content))
+	if d.Comma != 0 {
+		r.Comma = d.Comma
+	}
+	r.FieldsPerRecord = -1
+	return r
+}
+
+func (d Delimited) writer(w io.Writer) *csv.Writer {
+	cw := csv.NewWriter(w)
+	if d.Comma != 0 {
+		cw.Comma = d.Comma
+	}
+	cw.UseCRLF = d.UseCRLF
+	return cw
+}
+
+// ParseDelimited reads every row of content into a T, which must be a struct tagged as described on Delimited.
+func ParseDelimited[T any](content string, d Delimited) ([]T, error) {
+	var nothing T
+	codec, err := delimitedCodecFor(reflect.TypeOf(nothing))
+	if err != nil {
+		return nil, err
+	}
+
+	r := d.reader(content)
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read delimited content")
+	}
+
+	indices := codec.defaultIndices()
+	if d.Header && len(rows) > 0 {
+		indices, err = codec.headerIndices(rows[0])
+		if err != nil {
+			return nil, err
+		}
+		rows = rows[1:]
+	}
+
+	result := make([]T, len(rows))
+	for i, row := range rows {
+		err = codec.parse(row, indices, reflect.ValueOf(&result[i]).Elem())
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse row %d", i+1)
+		}
+	}
+	return result, nil
+}
+
+// GenerateDelimited writes the records, preceded by a header row if d.Header is set, to builder.
+func GenerateDelimited[T any](records []T, d Delimited, builder *strings.Builder) error {
+	var nothing T
+	codec, err := delimitedCodecFor(reflect.TypeOf(nothing))
+	if err != nil {
+		return err
+	}
+
+	w := d.writer(builder)
+	if d.Header {
+		header := make([]string, codec.width)
+		for _, col := range codec.columns {
+			header[col.column] = col.header
+		}
+		if err := w.Write(header); err != nil {
+			return err
+		}
+	}
+
+	for i, record := range records {
+		row, err := codec.generate(reflect.ValueOf(record))
+		if err != nil {
+			return errors.Wrapf(err, "could not generate row %d", i+1)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (c *delimitedCodec) defaultIndices() []int {
+	indices := make([]int, len(c.columns))
+	for i, col := range c.columns {
+		indices[i] = col.column
+	}
+	return indices
+}
+
+// headerIndices maps each column to its index in the header row.
+// Columns are found by name, except columns with a col tag and no header tag, which keep their col index.
+func (c *delimitedCodec) headerIndices(header []string) ([]int, error) {
+	byName := make(map[string]int, len(header))
+	for i, h := range header {
+		byName[strings.TrimSpace(h)] = i
+	}
+
+	indices := c.defaultIndices()
+	for i, col := range c.columns {
+		if col.byIndex {
+			continue
+		}
+		idx, ok := byName[col.header]
+		if !ok {
+			return nil, fmt.Errorf("header %s for %s not found", col.header, col.name)
+		}
+		indices[i] = idx
+	}
+	return indices, nil
+}
+
+func (c *delimitedCodec) parse(row []string, indices []int, target reflect.Value) error {
+	for i, col := range c.columns {
+		if col.parse == nil {
+			continue
+		}
+		if indices[i] >= len(row) {
+			return fmt.Errorf("no column %d for %s", indices[i]+1, col.name)
+		}
+
+		err := col.parse(target.Field(col.index), row[indices[i]], col.tag)
+		if err != nil {
+			return errors.Wrapf(err, "could not parse %s", col.name)
+		}
+	}
+	return nil
+}
+
+func (c *delimitedCodec) generate(source reflect.Value) ([]string, error) {
+	row := make([]string, c.width)
+	for _, col := range c.columns {
+		if col.toStr == nil {
+			continue
+		}
+		value, err := col.toStr(source.Field(col.index), col.tag)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not convert %s to string", col.name)
+		}
+		row[col.column], err = col.sanitize(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
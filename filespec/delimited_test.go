@@ -0,0 +1,73 @@
+package filespec
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testReportRow struct {
+	Reference string    `header:"Reference"`
+	Amount    int       `header:"Amount" type:"C" sign:"leading"`
+	Date      time.Time `header:"Action Date"`
+	Paid      bool      `header:"Paid" type:"A"`
+	Ignored   string    `col:"-"`
+}
+
+func TestGenerateDelimited(t *testing.T) {
+	rows := []testReportRow{
+		{Reference: "ABC, Ltd", Amount: 12345, Date: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), Paid: true},
+		{Reference: `Say "hi"`, Amount: -5, Date: time.Date(2023, 2, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	var b strings.Builder
+	assert.NoError(t, GenerateDelimited(rows, Delimited{Header: true}, &b))
+	assert.Equal(t, "Reference,Amount,Action Date,Paid\n"+
+		"\"ABC, Ltd\",+123.45,2023-02-01,Y\n"+
+		"\"Say \"\"hi\"\"\",-0.05,2023-02-02,N\n", b.String())
+
+	b.Reset()
+	assert.NoError(t, GenerateDelimited(rows[:1], Delimited{Comma: '|'}, &b))
+	assert.Equal(t, "ABC, Ltd|+123.45|2023-02-01|Y\n", b.String())
+}
+
+func TestParseDelimited(t *testing.T) {
+	content := "Paid|Action Date|Amount|Reference\n" +
+		"Y|2023-02-01|+123.45|\"ABC | Ltd\"\n" +
+		"N|2023-02-02|-0.05|XYZ\n"
+
+	rows, err := ParseDelimited[testReportRow](content, Delimited{Comma: '|', Header: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []testReportRow{
+		{Reference: "ABC | Ltd", Amount: 12345, Date: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), Paid: true},
+		{Reference: "XYZ", Amount: -5, Date: time.Date(2023, 2, 2, 0, 0, 0, 0, time.UTC)},
+	}, rows)
+
+	_, err = ParseDelimited[testReportRow]("Paid,Amount\nY,1.00\n", Delimited{Header: true})
+	assert.Error(t, err)
+
+	type byIndex struct {
+		Second int    `col:"2"`
+		First  string `col:"1"`
+	}
+	indexed, err := ParseDelimited[byIndex]("a,1\nb,2\n", Delimited{})
+	assert.NoError(t, err)
+	assert.Equal(t, []byIndex{{1, "a"}, {2, "b"}}, indexed)
+
+	_, err = ParseDelimited[byIndex]("a\n", Delimited{})
+	assert.Error(t, err)
+	indexed, err = ParseDelimited[byIndex]("x,y\na,1\n", Delimited{Header: true})
+	assert.NoError(t, err, "fields with a col tag keep their column")
+	assert.Equal(t, []byIndex{{1, "a"}}, indexed)
+
+	type byName struct {
+		Reference string
+		Amount    int
+	}
+	named, err := ParseDelimited[byName]("Amount,Reference\n5,abc\n", Delimited{Header: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []byName{{"abc", 5}}, named)
+	_, err = ParseDelimited[byName]("Amount,Ref\n5,abc\n", Delimited{Header: true})
+	assert.Error(t, err, "a field name missing from the header row is not read from its index")
+}
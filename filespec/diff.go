@@ -0,0 +1,218 @@
+package filespec
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"reflect"
+	"strings"
+)
+
+// DiffOptions configure how two fixed-width files are compared.
+type DiffOptions struct {
+	// Key is the name of the field that identifies a record in both files, e.g. a contract reference.
+	Key string
+	// Include selects the lines to compare, e.g. only detail records. If nil, all non-empty lines are compared.
+	Include func(line string) bool
+}
+
+// FileDiff is the result of comparing an old and a new file, e.g. a file we generated and the bank's echo of it.
+type FileDiff struct {
+	Added   []DiffRecord   `json:"added"`   // Records only in the new file
+	Removed []DiffRecord   `json:"removed"` // Records only in the old file
+	Changed []RecordChange `json:"changed"` // Records in both files with different field values
+}
+
+// DiffRecord is a record that is only in one of the files.
+type DiffRecord struct {
+	Key  string `json:"key"`
+	Line int    `json:"line"` // 1-based line number in the file the record is in
+	Text string `json:"text"`
+}
+
+// RecordChange is a record in both files, with the fields that differ.
+type RecordChange struct {
+	Key     string        `json:"key"`
+	OldLine int           `json:"oldLine"`
+	NewLine int           `json:"newLine"`
+	Fields  []FieldChange `json:"fields"`
+}
+
+// FieldChange is a field that differs between the old and new version of a record.
+// Old and New are the field's text as it appears in each file.
+type FieldChange struct {
+	Field string `json:"field"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// DiffFiles parses the old and new files as records of the type of record (a struct or pointer to one),
+// matches them by the key field, and returns the records that were added, removed or changed.
+// Fields are compared by their parsed values, so formatting differences that parse to the same value are ignored.
+func DiffFiles(record interface{}, oldContent, newContent string, opts DiffOptions) (*FileDiff, error) {
+	t := reflect.TypeOf(record)
+	if t == nil {
+		return nil, errors.New("cannot diff nil record")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	codec, err := codecFor(t)
+	if err != nil {
+		return nil, err
+	}
+	return diffFiles(codec, t, oldContent, newContent, opts)
+}
+
+// Diff compares two files with the format's layout, see DiffFiles.
+func (d *DynamicFormat) Diff(oldContent, newContent string, opts DiffOptions) (*FileDiff, error) {
+	return diffFiles(d.codec, d.valueType, oldContent, newContent, opts)
+}
+
+type diffLine struct {
+	number int
+	text   string
+	key    string
+	value  reflect.Value
+}
+
+func diffFiles(codec *recordCodec, t reflect.Type, oldContent, newContent string, opts DiffOptions) (*FileDiff, error) {
+	var key *fieldCodec
+	for i := range codec.fields {
+		if codec.fields[i].name == opts.Key {
+			key = &codec.fields[i]
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("key field %q is not in the record", opts.Key)
+	}
+
+	oldLines, err := readDiffLines(codec, t, *key, oldContent, opts.Include)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read old file")
+	}
+	newLines, err := readDiffLines(codec, t, *key, newContent, opts.Include)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read new file")
+	}
+
+	newByKey := make(map[string]diffLine, len(newLines))
+	for _, l := range newLines {
+		newByKey[l.key] = l
+	}
+
+	diff := &FileDiff{}
+	matched := make(map[string]bool, len(oldLines))
+	for _, o := range oldLines {
+		n, ok := newByKey[o.key]
+		if !ok {
+			diff.Removed = append(diff.Removed, DiffRecord{Key: o.key, Line: o.number, Text: o.text})
+			continue
+		}
+		matched[o.key] = true
+
+		change := RecordChange{Key: o.key, OldLine: o.number, NewLine: n.number}
+		for _, fc := range codec.fields {
+			if valuesEqual(o.value.Field(fc.index).Interface(), n.value.Field(fc.index).Interface()) {
+				continue
+			}
+			change.Fields = append(change.Fields, FieldChange{
+				Field: fc.name,
+				Start: fc.tag.Start,
+				End:   fc.tag.End,
+				Old:   fieldText(o.text, fc.tag),
+				New:   fieldText(n.text, fc.tag),
+			})
+		}
+		if len(change.Fields) > 0 {
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for _, n := range newLines {
+		if !matched[n.key] {
+			diff.Added = append(diff.Added, DiffRecord{Key: n.key, Line: n.number, Text: n.text})
+		}
+	}
+	return diff, nil
+}
+
+func readDiffLines(codec *recordCodec, t reflect.Type, key fieldCodec, content string, include func(string) bool) ([]diffLine, error) {
+	var result []diffLine
+	seen := make(map[string]int)
+	for i, text := range strings.Split(content, "\n") {
+		text = strings.TrimSuffix(text, "\r")
+		if strings.TrimSpace(text) == "" || include != nil && !include(text) {
+			continue
+		}
+
+		value := reflect.New(t).Elem()
+		if err := codec.parse(text, value); err != nil {
+			return nil, errors.Wrapf(err, "line %d", i+1)
+		}
+		k := strings.TrimSpace(fieldText(text, key.tag))
+		if prev, ok := seen[k]; ok {
+			return nil, fmt.Errorf("line %d has the same %s as line %d: %s", i+1, key.name, prev, k)
+		}
+		seen[k] = i + 1
+		result = append(result, diffLine{number: i + 1, text: text, key: k, value: value})
+	}
+	return result, nil
+}
+
+// fieldText returns the field's text in line, or as much of it as the line has.
+// Like parse, it counts runes if the line is not ASCII.
+func fieldText(line string, tag RecordTag) string {
+	var runes []rune
+	length := len(line)
+	if !isASCII(line) {
+		runes = []rune(line)
+		length = len(runes)
+	}
+
+	start, end := tag.Start-1, tag.End
+	if end > length {
+		end = length
+	}
+	if start >= end {
+		return ""
+	}
+	if runes != nil {
+		return string(runes[start:end])
+	}
+	return line[start:end]
+}
+
+// Empty returns true if the files had the same records.
+func (d FileDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Text returns a human-readable report of the differences.
+func (d FileDiff) Text() string {
+	if d.Empty() {
+		return "No differences\n"
+	}
+
+	var b strings.Builder
+	for _, r := range d.Removed {
+		fmt.Fprintf(&b, "- %s (old line %d): %s\n", r.Key, r.Line, r.Text)
+	}
+	for _, r := range d.Added {
+		fmt.Fprintf(&b, "+ %s (new line %d): %s\n", r.Key, r.Line, r.Text)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "~ %s (old line %d, new line %d):\n", c.Key, c.OldLine, c.NewLine)
+		for _, f := range c.Fields {
+			fmt.Fprintf(&b, "    %s [%d-%d]: %q -> %q\n", f.Field, f.Start, f.End, f.Old, f.New)
+		}
+	}
+	fmt.Fprintf(&b, "%d removed, %d added, %d changed\n", len(d.Removed), len(d.Added), len(d.Changed))
+	return b.String()
+}
+
+// JSON returns the differences as indented JSON.
+func (d FileDiff) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
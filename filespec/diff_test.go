@@ -0,0 +1,63 @@
+package filespec
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestDiffFiles(t *testing.T) {
+	oldFile := "H HEADER\r\n" +
+		"JANE      000001234{20230201Y\r\n" +
+		"JOE       000000050{20230201N\r\n" +
+		"ANNE      000000700{20230201Y\r\n"
+	newFile := "H HEADER 2\n" +
+		"ANNE      000000700{20230201Y\n" +
+		"JANE      000001234}20230202Y\n" +
+		"PETER     000000001{20230201Y\n" +
+		"\n"
+	opts := DiffOptions{Key: "Name", Include: func(line string) bool { return !strings.HasPrefix(line, "H ") }}
+
+	diff, err := DiffFiles(testLayoutRecord{}, oldFile, newFile, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, &FileDiff{
+		Added:   []DiffRecord{{Key: "PETER", Line: 4, Text: "PETER     000000001{20230201Y"}},
+		Removed: []DiffRecord{{Key: "JOE", Line: 3, Text: "JOE       000000050{20230201N"}},
+		Changed: []RecordChange{{Key: "JANE", OldLine: 2, NewLine: 3, Fields: []FieldChange{
+			{Field: "Amount", Start: 11, End: 20, Old: "000001234{", New: "000001234}"},
+			{Field: "Date", Start: 21, End: 28, Old: "20230201", New: "20230202"},
+		}}},
+	}, diff)
+	assert.False(t, diff.Empty())
+
+	assert.Equal(t, "- JOE (old line 3): JOE       000000050{20230201N\n"+
+		"+ PETER (new line 4): PETER     000000001{20230201Y\n"+
+		"~ JANE (old line 2, new line 3):\n"+
+		"    Amount [11-20]: \"000001234{\" -> \"000001234}\"\n"+
+		"    Date [21-28]: \"20230201\" -> \"20230202\"\n"+
+		"1 removed, 1 added, 1 changed\n", diff.Text())
+
+	j, err := diff.JSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(j), `"field": "Amount",`)
+
+	l, err := LayoutOf(testLayoutRecord{})
+	assert.NoError(t, err)
+	format, err := NewDynamicFormat(l)
+	assert.NoError(t, err)
+	dynamicDiff, err := format.Diff(oldFile, newFile, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, diff, dynamicDiff)
+
+	same, err := DiffFiles(&testLayoutRecord{}, oldFile, oldFile, opts)
+	assert.NoError(t, err)
+	assert.True(t, same.Empty())
+	assert.Equal(t, "No differences\n", same.Text())
+
+	_, err = DiffFiles(testLayoutRecord{}, oldFile, newFile, DiffOptions{Key: "Missing"})
+	assert.Error(t, err)
+	_, err = DiffFiles(testLayoutRecord{}, oldFile+oldFile, newFile, opts)
+	assert.Error(t, err, "duplicate keys")
+	_, err = DiffFiles(testLayoutRecord{}, oldFile, newFile, DiffOptions{Key: "Name"})
+	assert.Error(t, err, "header lines don't parse")
+}
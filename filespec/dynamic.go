@@ -0,0 +1,166 @@
+package filespec
+
+import (
+	"fmt"
+	"github.com/Direct-Debit/go-commons/fileio"
+	"github.com/pkg/errors"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// DynamicRecord holds the values of a record whose layout is only known at runtime, keyed by field name.
+// Values have the Go type of their field's kind: string, int64, uint64, float64, bool or time.Time.
+type DynamicRecord map[string]interface{}
+
+// DynamicFormat parses and generates DynamicRecords for a Layout,
+// using the same codecs as ParseRecord and GenerateLine use for record structs.
+type DynamicFormat struct {
+	layout    Layout
+	codec     *recordCodec
+	valueType reflect.Type // A struct with a field for every layout field, used to hold values while converting
+}
+
+// NewDynamicFormat validates and compiles the layout.
+func NewDynamicFormat(layout Layout) (*DynamicFormat, error) {
+	codec, err := layout.compile()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]reflect.StructField, len(layout.Fields))
+	for i, f := range layout.Fields {
+		fields[i] = reflect.StructField{Name: fmt.Sprintf("F%d", i), Type: kindTypes[f.Kind]}
+	}
+	return &DynamicFormat{
+		layout:    layout,
+		codec:     codec,
+		valueType: reflect.StructOf(fields),
+	}, nil
+}
+
+// LoadLayoutFile reads a JSON layout, as written by Layout.JSON, from the given FileStore.
+// To load a layout from config instead, store the JSON as a string value and pass it to LoadLayout.
+func LoadLayoutFile(store fileio.FileStore, path string) (Layout, error) {
+	content, err := store.Load(path)
+	if err != nil {
+		return Layout{}, errors.Wrapf(err, "could not load layout %s", path)
+	}
+	return LoadLayout([]byte(content))
+}
+
+// Layout returns the layout the format was created with.
+func (d *DynamicFormat) Layout() Layout {
+	return d.layout
+}
+
+// ParseRecord reads the fixed-width line into a new DynamicRecord with a value for every field.
+func (d *DynamicFormat) ParseRecord(line string) (DynamicRecord, error) {
+	values := reflect.New(d.valueType).Elem()
+	if err := d.codec.parse(line, values); err != nil {
+		return nil, err
+	}
+
+	record := make(DynamicRecord, len(d.layout.Fields))
+	for i, f := range d.layout.Fields {
+		record[f.Name] = values.Field(i).Interface()
+	}
+	return record, nil
+}
+
+// GenerateLine writes the record as a fixed-width line, including the trailing newline, to builder.
+// Fields missing from the record get their zero value. Values may be of any type convertible to the field's kind,
+// but keys that are not in the layout are an error, to catch misspelt field names.
+func (d *DynamicFormat) GenerateLine(record DynamicRecord, builder *strings.Builder) error {
+	values := reflect.New(d.valueType).Elem()
+	found := 0
+	for i, f := range d.layout.Fields {
+		v, ok := record[f.Name]
+		if !ok {
+			continue
+		}
+		found++
+		if err := setDynamicValue(values.Field(i), v); err != nil {
+			return errors.Wrapf(err, "invalid value for %s", f.Name)
+		}
+	}
+
+	if found < len(record) {
+		for name := range record {
+			if !d.hasField(name) {
+				return fmt.Errorf("%s is not a field of layout %s", name, d.layout.Name)
+			}
+		}
+	}
+	return d.codec.generate(values, builder)
+}
+
+func (d *DynamicFormat) hasField(name string) bool {
+	for _, f := range d.layout.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setDynamicValue sets field to val, converting between numeric types where needed.
+// Conversions Go allows but that change the meaning of the value, like int to string, are refused.
+func setDynamicValue(field reflect.Value, val interface{}) error {
+	if val == nil {
+		return nil
+	}
+	v := reflect.ValueOf(val)
+	ft := field.Type()
+
+	compatible := v.Type() == ft
+	switch ft.Kind() {
+	case reflect.String:
+		compatible = v.Kind() == reflect.String
+	case reflect.Int64, reflect.Uint64, reflect.Float64:
+		compatible = isNumber(v.Kind())
+	case reflect.Bool:
+		compatible = v.Kind() == reflect.Bool
+	}
+	if !compatible || !v.Type().ConvertibleTo(ft) {
+		return fmt.Errorf("%v is not a %v", val, ft)
+	}
+
+	if err := checkIntegerRange(v, ft.Kind()); err != nil {
+		return err
+	}
+	field.Set(v.Convert(ft))
+	return nil
+}
+
+// checkIntegerRange refuses values that an Int64 or Uint64 field can't hold exactly:
+// floats with a fraction, and values out of the field's range.
+func checkIntegerRange(v reflect.Value, kind reflect.Kind) error {
+	if kind != reflect.Int64 && kind != reflect.Uint64 {
+		return nil
+	}
+	switch {
+	case v.CanFloat():
+		f := v.Float()
+		if f != math.Trunc(f) {
+			return fmt.Errorf("%v is not a whole number", f)
+		}
+		if kind == reflect.Int64 && (f < math.MinInt64 || f >= math.MaxInt64) ||
+			kind == reflect.Uint64 && (f < 0 || f >= math.MaxUint64) {
+			return fmt.Errorf("%v is out of range for %v", f, kind)
+		}
+	case v.CanInt():
+		if kind == reflect.Uint64 && v.Int() < 0 {
+			return fmt.Errorf("%v is negative", v.Int())
+		}
+	case v.CanUint():
+		if kind == reflect.Int64 && v.Uint() > math.MaxInt64 {
+			return fmt.Errorf("%v is out of range for %v", v.Uint(), kind)
+		}
+	}
+	return nil
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uint64 || k == reflect.Float32 || k == reflect.Float64
+}
@@ -0,0 +1,72 @@
+package filespec
+
+import (
+	"github.com/Direct-Debit/go-commons/fileio"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testLayoutJSON = `{"name": "Bank X debit", "fields": [
+	{"name": "Account Holder", "start": 1, "length": 10, "type": "A"},
+	{"name": "Amount", "kind": "int", "start": 11, "length": 9, "type": "N", "sign": "leading"},
+	{"name": "Action Date", "kind": "date", "start": 20, "length": 8, "type": "N"},
+	{"name": "Rate", "kind": "float", "start": 28, "length": 5, "type": "N", "decimals": 3},
+	{"name": "Sequence", "kind": "uint", "start": 33, "length": 4, "type": "N"},
+	{"name": "Tracking", "kind": "bool", "start": 37, "length": 1, "type": "A"}
+]}`
+
+func TestDynamicFormat(t *testing.T) {
+	store := fileio.SimpleFileStore{BasePath: t.TempDir()}
+	assert.NoError(t, store.Save("layouts/bank-x.json", testLayoutJSON))
+
+	layout, err := LoadLayoutFile(store, "layouts/bank-x.json")
+	assert.NoError(t, err)
+	format, err := NewDynamicFormat(layout)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bank X debit", format.Layout().Name)
+
+	var b strings.Builder
+	err = format.GenerateLine(DynamicRecord{
+		"Account Holder": "Jane",
+		"Amount":         -12345,
+		"Action Date":    time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+		"Rate":           1.25,
+		"Sequence":       7,
+		"Tracking":       true,
+	}, &b)
+	assert.NoError(t, err)
+	line := "JANE      -0001234520230201012500007Y"
+	assert.Equal(t, line+"\n", b.String())
+
+	record, err := format.ParseRecord(line)
+	assert.NoError(t, err)
+	assert.Equal(t, DynamicRecord{
+		"Account Holder": "JANE      ",
+		"Amount":         int64(-12345),
+		"Action Date":    time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+		"Rate":           1.25,
+		"Sequence":       uint64(7),
+		"Tracking":       true,
+	}, record)
+
+	b.Reset()
+	assert.NoError(t, format.GenerateLine(DynamicRecord{"Account Holder": "Joe"}, &b))
+	assert.Equal(t, "JOE       +0000000000010101000000000N\n", b.String())
+
+	assert.Error(t, format.GenerateLine(DynamicRecord{"Acount Holder": "Joe"}, &b))
+	assert.Error(t, format.GenerateLine(DynamicRecord{"Account Holder": 7}, &b))
+	assert.Error(t, format.GenerateLine(DynamicRecord{"Amount": "7"}, &b))
+	assert.Error(t, format.GenerateLine(DynamicRecord{"Sequence": -7}, &b))
+	assert.Error(t, format.GenerateLine(DynamicRecord{"Amount": 12.7}, &b), "fractions are not truncated")
+	assert.Error(t, format.GenerateLine(DynamicRecord{"Amount": uint64(math.MaxInt64) + 1}, &b), "large uints don't wrap")
+	assert.Error(t, format.GenerateLine(DynamicRecord{"Sequence": 1.5}, &b))
+	assert.Error(t, format.GenerateLine(DynamicRecord{"Sequence": 1e20}, &b))
+	assert.NoError(t, format.GenerateLine(DynamicRecord{"Amount": 12.0, "Sequence": uint8(3)}, &b))
+	assert.Error(t, format.GenerateLine(DynamicRecord{"Action Date": "2023-02-01"}, &b))
+
+	_, err = LoadLayoutFile(store, "layouts/missing.json")
+	assert.Error(t, err)
+}
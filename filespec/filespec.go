@@ -1,22 +1,47 @@
 package filespec
 
 import (
+	"encoding"
 	"fmt"
-	"github.com/Direct-Debit/go-commons/stdext"
 	"github.com/pkg/errors"
 	"reflect"
 	"strconv"
 	"strings"
 	"time"
+)
+
+// FixedWidthMarshaler is implemented by types that can write themselves into a fixed-width field.
+// The returned value is still padded and validated according to the field's type tag.
+type FixedWidthMarshaler interface {
+	MarshalFixedWidth(tag RecordTag) (string, error)
+}
 
-	"github.com/Direct-Debit/go-commons/format"
+// FixedWidthUnmarshaler is implemented by types that can read themselves from a fixed-width field.
+// The value passed in is the raw, untrimmed content of the field.
+type FixedWidthUnmarshaler interface {
+	UnmarshalFixedWidth(value string, tag RecordTag) error
+}
+
+var (
+	timeType                  = reflect.TypeOf(time.Time{})
+	fixedWidthMarshalerType   = reflect.TypeOf((*FixedWidthMarshaler)(nil)).Elem()
+	fixedWidthUnmarshalerType = reflect.TypeOf((*FixedWidthUnmarshaler)(nil)).Elem()
+	textMarshalerType         = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType       = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
 )
 
 type RecordTag struct {
 	Start  int
 	End    int
 	Type   string
-	Format string
+	Format string // Date layout, or the name of a format registered with format.RegisterDateFormat
+	Sign   string // How numeric fields store their sign, see SignLeading and friends
+	// Decimals is the number of implied decimal places of numeric fields, 2 by default for currency fields ("C").
+	// Currency fields are written with a decimal point, but read without one the last digits are the decimals,
+	// so "00001234" is 1234 cents. Floats with more decimals are rounded, or refused with OverflowError.
+	Decimals int
+	Charset  string // Name of the set of characters allowed in generated values, see CharsetBank and friends
+	Overflow string // What to do with values that are too long or too precise, see OverflowTruncate and OverflowError
 }
 
 func ParseRecordTag(f reflect.StructField) (RecordTag, error) {
@@ -34,239 +59,74 @@ func ParseRecordTag(f reflect.StructField) (RecordTag, error) {
 		return RecordTag{}, errors.Wrapf(err, "could not parse field end index")
 	}
 
-	return RecordTag{
-		Start:  start,
-		End:    end,
-		Type:   f.Tag.Get("type"),
-		Format: f.Tag.Get("format"),
-	}, nil
+	tag, err := parseValueTags(f)
+	tag.Start, tag.End = start, end
+	return tag, err
 }
 
-func (r RecordTag) Length() int {
-	return r.End - r.Start + 1
-}
-
-func parseStruct(field reflect.Value, strVal string, tag RecordTag) error {
-	switch field.Type() {
-	case reflect.TypeOf(time.Time{}):
-		if len(strings.TrimSpace(strVal)) == 0 {
-			field.Set(reflect.ValueOf(time.Time{}))
-			return nil
+// parseValueTags reads the tags that describe how a field's value is written, regardless of where it is written.
+func parseValueTags(f reflect.StructField) (RecordTag, error) {
+	var err error
+	decimals := 0
+	if d := f.Tag.Get("decimals"); d != "" {
+		decimals, err = strconv.Atoi(d)
+		if err != nil || decimals < 0 {
+			return RecordTag{}, fmt.Errorf("decimals tag invalid for %s", f.Name)
 		}
+	}
 
-		timeFormat := tag.Format
-		if len(timeFormat) == 0 {
-			switch tag.Length() {
-			case 6:
-				timeFormat = format.DateShort6
-			case 8:
-				timeFormat = format.DateShort8
-			case 4:
-				timeFormat = format.MMYY
-			default:
-				return fmt.Errorf("invalid time length: %d", tag.Length())
-			}
-		}
-		timeVal, err := time.Parse(timeFormat, strVal)
-		if err != nil {
-			return errors.Wrapf(err, "could not parse time %s with %s", strVal, timeFormat)
-		}
-		field.Set(reflect.ValueOf(timeVal))
-	default:
-		return errors.New("unsupported struct in parse")
+	tag := RecordTag{
+		Type:     f.Tag.Get("type"),
+		Format:   f.Tag.Get("format"),
+		Sign:     f.Tag.Get("sign"),
+		Decimals: decimals,
+		Charset:  f.Tag.Get("charset"),
+		Overflow: f.Tag.Get("overflow"),
+	}
+	return tag, tag.validate(f.Name)
+}
+
+// validate checks the options that have a fixed set of values.
+func (r RecordTag) validate(name string) error {
+	if r.Decimals < 0 {
+		return fmt.Errorf("decimals invalid for %s", name)
+	}
+	if !validSign(r.Sign) {
+		return fmt.Errorf("sign invalid for %s", name)
+	}
+	if r.Overflow != "" && r.Overflow != OverflowTruncate && r.Overflow != OverflowError {
+		return fmt.Errorf("overflow invalid for %s", name)
 	}
 	return nil
 }
 
+// Length is the width of a fixed-width field. It is zero for delimited columns, which have no fixed width.
+func (r RecordTag) Length() int {
+	return r.End - r.Start + 1
+}
+
+// ParseRecord reads the fixed-width line into the struct that target points to.
+// The layout of the struct is compiled and cached the first time it is used, see Register.
 func ParseRecord(line string, target interface{}) error {
 	if reflect.ValueOf(target).Kind() != reflect.Ptr {
 		return errors.New("target is not a pointer")
 	}
 
-	targetType := reflect.TypeOf(target).Elem()
 	targetValue := reflect.ValueOf(target).Elem()
-
-	for i := 0; i < targetValue.NumField(); i++ {
-		field := targetValue.Field(i)
-		fieldType := targetType.Field(i)
-
-		tag, err := ParseRecordTag(fieldType)
-		if err != nil {
-			return errors.Wrapf(err, "invalid tag on %s", fieldType.Name)
-		}
-
-		if len(line) < tag.End {
-			tag.End = len(line)
-			if tag.End < tag.Start-1 {
-				return fmt.Errorf("line too short: %s", line)
-			}
-		}
-		strVal := line[tag.Start-1 : tag.End]
-
-		switch field.Kind() {
-		case reflect.String:
-			val := strVal
-			if tag.Type == "N" {
-				val = strings.TrimSpace(val)
-				val = strings.TrimLeft(val, "0")
-			}
-			field.SetString(val)
-		case reflect.Struct:
-			err = parseStruct(field, strVal, tag)
-			if err != nil {
-				return errors.Wrapf(err, "could not parse struct for %s", fieldType.Name)
-			}
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			intVal, err := strconv.ParseInt(strVal, 10, 64)
-			if err != nil {
-				return errors.Wrapf(err, "could not parse int for %s", fieldType.Name)
-			}
-			field.SetInt(intVal)
-		case reflect.Bool:
-			switch tag.Type {
-			case "N":
-				intVal, err := strconv.ParseInt(strVal, 10, 64)
-				if err != nil {
-					return errors.Wrapf(err, "could not parse bool for %s", fieldType.Name)
-				}
-				field.SetBool(intVal > 0)
-			case "AN", "A":
-				field.SetBool(true)
-				val := strings.ToLower(strings.TrimSpace(strVal))
-				for _, s := range []string{
-					"n", "no",
-					"f", "false",
-					"0",
-				} {
-					if s == val {
-						field.SetBool(false)
-					}
-				}
-			}
-		case reflect.Float64:
-			floatVal, err := strconv.ParseFloat(strVal, 64)
-			if err != nil {
-				return errors.Wrapf(err, "could not parse float for %s", fieldType.Name)
-			}
-			field.SetFloat(floatVal)
-		case reflect.Float32:
-			floatVal, err := strconv.ParseFloat(strVal, 32)
-			if err != nil {
-				return errors.Wrapf(err, "could not parse float for %s", fieldType.Name)
-			}
-			field.SetFloat(floatVal)
-		}
-	}
-
-	return nil
-}
-
-func structValToStr(val reflect.Value, tag RecordTag) (string, error) {
-	switch val.Type() {
-	case reflect.TypeOf(time.Time{}):
-		date := val.Interface().(time.Time)
-		if tag.Format != "" {
-			return date.Format(tag.Format), nil
-		} else {
-			switch tag.Length() {
-			case 10:
-				return date.Format(format.DateShortSlashes), nil
-			case 8:
-				return date.Format(format.DateShort8), nil
-			default:
-				return date.Format(format.DateShort6), nil
-			}
-		}
+	codec, err := codecFor(targetValue.Type())
+	if err != nil {
+		return err
 	}
-	return "", fmt.Errorf("couldn't convert %v to string", val.Type())
+	return codec.parse(line, targetValue)
 }
 
+// GenerateLine writes source as a fixed-width line, including the trailing newline, to builder.
+// The layout of the struct is compiled and cached the first time it is used, see Register.
 func GenerateLine(source interface{}, builder *strings.Builder) error {
-	sourceType := reflect.TypeOf(source)
-	sourceValue := reflect.ValueOf(source)
-
-	lastFieldType := sourceType.Field(sourceType.NumField() - 1)
-	lastTag, err := ParseRecordTag(lastFieldType)
+	sourceValue := reflect.Indirect(reflect.ValueOf(source))
+	codec, err := codecFor(sourceValue.Type())
 	if err != nil {
-		return errors.Wrapf(err, "invalid tag on %s", lastFieldType.Name)
-	}
-
-	line := make([]rune, lastTag.End+1) // +1 for newline character
-	for i := range line {
-		line[i] = ' '
+		return err
 	}
-
-	for i := 0; i < sourceValue.NumField(); i++ {
-		fieldType := sourceType.Field(i)
-		fieldValue := sourceValue.Field(i)
-
-		tag, err := ParseRecordTag(fieldType)
-		if err != nil {
-			return errors.Wrapf(err, "invalid tag on %s", fieldType.Name)
-		}
-
-		var value string
-		switch fieldValue.Kind() {
-		case reflect.String:
-			value = fieldValue.String()
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			switch tag.Type {
-			case "C":
-				fVal := stdext.RoundTo(float64(fieldValue.Int())/100, 2)
-				value = fmt.Sprintf("%.2f", fVal)
-			default:
-				value = strconv.FormatInt(fieldValue.Int(), 10)
-			}
-		case reflect.Struct:
-			value, err = structValToStr(fieldValue, tag)
-			if err != nil {
-				return errors.Wrapf(err, "could not parse struct type to string")
-			}
-		case reflect.Bool:
-			switch tag.Type {
-			case "N":
-				value = "1"
-				if !fieldValue.Bool() {
-					value = "0"
-				}
-			case "A", "AN":
-				value = "Y"
-				if !fieldValue.Bool() {
-					value = "N"
-				}
-			}
-		}
-
-		switch tag.Type {
-		case "N":
-			value = fmt.Sprintf("%0*s", tag.Length(), value)
-			_, err = strconv.Atoi(value)
-			if err != nil && !strings.Contains(value, "TEST") {
-				return errors.Wrapf(err, "could not convert integer value from %s", fieldType.Name)
-			}
-		case "C":
-			value = fmt.Sprintf("%0*s", tag.Length(), value)
-			_, err = strconv.ParseFloat(value, 64)
-			if err != nil && !strings.Contains(value, "TEST") {
-				return errors.Wrapf(err, "could not convert float value from %s", fieldType.Name)
-			}
-		case "A", "AN":
-			value = strings.ToUpper(value)
-			switch tag.Format {
-			case "align-right":
-				value = fmt.Sprintf("%*s", tag.Length(), value)
-			default:
-				value = fmt.Sprintf("%-*s", tag.Length(), value) // Default to left align
-			}
-		}
-
-		for idx, c := range value {
-			lineIdx := tag.Start - 1 + idx
-			line[lineIdx] = c
-		}
-	}
-
-	line[len(line)-1] = '\n'
-	builder.WriteString(string(line))
-	return nil
+	return codec.generate(sourceValue, builder)
 }
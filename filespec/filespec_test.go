@@ -0,0 +1,256 @@
+package filespec
+
+import (
+	"fmt"
+	"github.com/Direct-Debit/go-commons/format"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAccountNumber string
+
+func (a testAccountNumber) MarshalFixedWidth(tag RecordTag) (string, error) {
+	return fmt.Sprintf("%0*s", tag.Length(), string(a)), nil
+}
+
+func (a *testAccountNumber) UnmarshalFixedWidth(value string, _ RecordTag) error {
+	*a = testAccountNumber(strings.TrimSpace(value))
+	return nil
+}
+
+type testMoney struct {
+	cents int64
+}
+
+func (m testMoney) MarshalText() ([]byte, error) {
+	return []byte(strconv.FormatInt(m.cents, 10)), nil
+}
+
+func (m *testMoney) UnmarshalText(text []byte) error {
+	c, err := strconv.ParseInt(string(text), 10, 64)
+	m.cents = c
+	return err
+}
+
+type testRecord struct {
+	Name    string            `pos:"1-10" type:"A"`
+	Account testAccountNumber `pos:"11-21" type:"N"`
+	Amount  testMoney         `pos:"22-29" type:"N"`
+	Date    time.Time         `pos:"30-37" type:"N"`
+	Active  bool              `pos:"38-38" type:"A"`
+}
+
+func TestGenerateLine(t *testing.T) {
+	r := testRecord{
+		Name:    "Jane",
+		Account: "62123456789",
+		Amount:  testMoney{12345},
+		Date:    time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+		Active:  true,
+	}
+
+	var b strings.Builder
+	assert.NoError(t, GenerateLine(r, &b))
+	assert.Equal(t, "JANE      621234567890001234520230201Y\n", b.String())
+}
+
+func TestParseRecord(t *testing.T) {
+	var r testRecord
+	err := ParseRecord("JANE      621234567890001234520230201Y", &r)
+	assert.NoError(t, err)
+	assert.Equal(t, "JANE      ", r.Name)
+	assert.Equal(t, testAccountNumber("62123456789"), r.Account)
+	assert.Equal(t, int64(12345), r.Amount.cents)
+	assert.Equal(t, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), r.Date)
+	assert.True(t, r.Active)
+
+	err = ParseRecord("JANE      6212345678900012X4520230201Y", &r)
+	assert.Error(t, err)
+}
+
+func TestParseRecordUnsupportedStruct(t *testing.T) {
+	var r struct {
+		Field struct{ A int } `pos:"1-2"`
+	}
+	assert.Error(t, ParseRecord("12", &r))
+}
+
+func TestRegister(t *testing.T) {
+	assert.NoError(t, Register(testRecord{}))
+	assert.NoError(t, Register(&testRecord{}))
+
+	var overlapping struct {
+		A string `pos:"1-5"`
+		B string `pos:"5-8"`
+	}
+	assert.Error(t, Register(overlapping))
+
+	var outOfOrder struct {
+		A string `pos:"6-8"`
+		B string `pos:"1-5"`
+	}
+	assert.Error(t, Register(outOfOrder))
+
+	var badPos struct {
+		A string `pos:"6-1"`
+	}
+	assert.Error(t, Register(badPos))
+
+	var missingTag struct {
+		A string
+	}
+	assert.Error(t, Register(missingTag))
+	assert.Error(t, Register(7))
+}
+
+var benchRecord = testRecord{
+	Name:    "Jane",
+	Account: "62123456789",
+	Amount:  testMoney{12345},
+	Date:    time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+	Active:  true,
+}
+
+const benchLine = "JANE      621234567890001234520230201Y"
+
+func BenchmarkGenerateLine(b *testing.B) {
+	var builder strings.Builder
+	for i := 0; i < b.N; i++ {
+		builder.Reset()
+		_ = GenerateLine(benchRecord, &builder)
+	}
+}
+
+// BenchmarkGenerateLineUncached compiles the layout on every call, like GenerateLine did before codecs were cached.
+func BenchmarkGenerateLineUncached(b *testing.B) {
+	var builder strings.Builder
+	v := reflect.ValueOf(benchRecord)
+	for i := 0; i < b.N; i++ {
+		builder.Reset()
+		c, _ := compileRecord(v.Type())
+		_ = c.generate(v, &builder)
+	}
+}
+
+func BenchmarkParseRecord(b *testing.B) {
+	var r testRecord
+	for i := 0; i < b.N; i++ {
+		_ = ParseRecord(benchLine, &r)
+	}
+}
+
+// BenchmarkParseRecordUncached compiles the layout on every call, like ParseRecord did before codecs were cached.
+func BenchmarkParseRecordUncached(b *testing.B) {
+	var r testRecord
+	v := reflect.ValueOf(&r).Elem()
+	for i := 0; i < b.N; i++ {
+		c, _ := compileRecord(v.Type())
+		_ = c.parse(benchLine, v)
+	}
+}
+
+type testSignedRecord struct {
+	Leading   int     `pos:"1-6" type:"N" sign:"leading"`
+	Trailing  int64   `pos:"7-12" type:"N" sign:"trailing"`
+	Overpunch int32   `pos:"13-18" type:"N" sign:"overpunch"`
+	LeadPunch int     `pos:"19-24" type:"N" sign:"leading-overpunch"`
+	Cents     int     `pos:"25-33" type:"C" sign:"leading"`
+	Implied   float64 `pos:"34-40" type:"N" decimals:"3"`
+	Unsigned  uint    `pos:"41-45" type:"N"`
+	Small     uint8   `pos:"46-48" type:"N"`
+}
+
+func TestSignedNumbers(t *testing.T) {
+	r := testSignedRecord{
+		Leading:   -123,
+		Trailing:  456,
+		Overpunch: -12340,
+		LeadPunch: 98765,
+		Cents:     -12345,
+		Implied:   12.5,
+		Unsigned:  42,
+		Small:     255,
+	}
+	line := "-0012300456+01234}{98765-00123.45001250000042255"
+
+	var b strings.Builder
+	assert.NoError(t, GenerateLine(r, &b))
+	assert.Equal(t, line+"\n", b.String())
+
+	var parsed testSignedRecord
+	assert.NoError(t, ParseRecord(line, &parsed))
+	assert.Equal(t, r, parsed)
+
+	assert.Error(t, ParseRecord("*0012300456+01234}{98765-00123.45001250000042255", &parsed))
+	assert.Error(t, ParseRecord("-0012300456+01234}{98765-00123.45001250000042256", &parsed))
+}
+
+func TestNumberOverflowAndSign(t *testing.T) {
+	var b strings.Builder
+	assert.Error(t, GenerateLine(struct {
+		A uint `pos:"1-2" type:"N"`
+	}{100}, &b))
+	assert.Error(t, GenerateLine(struct {
+		A int `pos:"1-5" type:"C"`
+	}{-100}, &b))
+	assert.Error(t, Register(struct {
+		A int `pos:"1-5" type:"N" sign:"sideways"`
+	}{}))
+
+	assert.Error(t, GenerateLine(struct {
+		A float64 `pos:"1-5" type:"N" overflow:"error"`
+	}{12.34}, &b), "a float with more decimals than the field is refused with overflow:error")
+	assert.Error(t, GenerateLine(struct {
+		A float32 `pos:"1-5" type:"N" decimals:"2" overflow:"error"`
+	}{1.125}, &b))
+	b.Reset()
+	assert.NoError(t, GenerateLine(struct {
+		A float64 `pos:"1-5" type:"N"`
+		B float64 `pos:"6-10" type:"N" decimals:"1"`
+	}{12.5, 1.25}, &b), "floats are rounded by default")
+	assert.Equal(t, "0001300013\n", b.String())
+	b.Reset()
+	assert.NoError(t, GenerateLine(struct {
+		A float64 `pos:"1-5" type:"N"`
+		B float32 `pos:"6-10" type:"N" decimals:"2"`
+	}{12, 1.1}, &b))
+	assert.Equal(t, "0001200110\n", b.String())
+
+	var r struct {
+		A int `pos:"1-8" type:"C"`
+		B int `pos:"9-12" type:"N" sign:"trailing"`
+	}
+	assert.NoError(t, ParseRecord("00012.50    ", &r))
+	assert.Equal(t, 1250, r.A)
+	assert.Equal(t, 0, r.B)
+	assert.NoError(t, ParseRecord("00001234    ", &r))
+	assert.Equal(t, 1234, r.A, "currency without a decimal point is in cents")
+}
+
+type namedDateRecord struct {
+	Action time.Time `pos:"1-10" format:"filespec-test-bank"`
+	Due    time.Time `pos:"11-20"`
+}
+
+func TestNamedDateFormat(t *testing.T) {
+	assert.NoError(t, format.RegisterDateFormat("filespec-test-bank", format.DateFormat{
+		Layouts: []string{format.DDsMMsYYYY, format.DateShortDashes},
+	}))
+
+	r := namedDateRecord{
+		Action: time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+		Due:    time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+	var b strings.Builder
+	assert.NoError(t, GenerateLine(r, &b))
+	assert.Equal(t, "01/02/20232023/02/01\n", b.String())
+
+	var parsed namedDateRecord
+	assert.NoError(t, ParseRecord("2023-02-012023/02/01", &parsed))
+	assert.Equal(t, r, parsed)
+	CheckRoundTrip(t, namedDateRecord{}, RoundTripOptions{})
+}
@@ -0,0 +1,262 @@
+package filespec
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/Direct-Debit/go-commons/format"
+	"github.com/pkg/errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kinds of values a LayoutField can hold.
+const (
+	KindString = "string"
+	KindInt    = "int"
+	KindUint   = "uint"
+	KindFloat  = "float"
+	KindBool   = "bool"
+	KindDate   = "date"
+)
+
+var kindTypes = map[string]reflect.Type{
+	KindString: reflect.TypeOf(""),
+	KindInt:    reflect.TypeOf(int64(0)),
+	KindUint:   reflect.TypeOf(uint64(0)),
+	KindFloat:  reflect.TypeOf(float64(0)),
+	KindBool:   reflect.TypeOf(false),
+	KindDate:   reflect.TypeOf(time.Time{}),
+}
+
+// Layout describes the fields of a fixed-width record, in order.
+// It can be generated from a tagged record struct with LayoutOf, or loaded from JSON with LoadLayout.
+type Layout struct {
+	Name   string        `json:"name"`
+	Fields []LayoutField `json:"fields"`
+}
+
+// LayoutField describes a single field of a Layout. The options have the same meaning as the record struct tags.
+type LayoutField struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Length   int    `json:"length"`
+	Type     string `json:"type,omitempty"`
+	Format   string `json:"format,omitempty"`
+	Sign     string `json:"sign,omitempty"`
+	Decimals int    `json:"decimals,omitempty"`
+	Charset  string `json:"charset,omitempty"`
+	Overflow string `json:"overflow,omitempty"`
+}
+
+// Tag returns the RecordTag equivalent of the field.
+func (f LayoutField) Tag() RecordTag {
+	return RecordTag{
+		Start:    f.Start,
+		End:      f.End,
+		Type:     f.Type,
+		Format:   f.Format,
+		Sign:     f.Sign,
+		Decimals: f.Decimals,
+		Charset:  f.Charset,
+		Overflow: f.Overflow,
+	}
+}
+
+// LayoutOf describes the layout of the given record struct (or pointer to one).
+// Date fields without a format tag get the format they are generated with.
+// Fields with custom codecs are described as strings.
+func LayoutOf(record interface{}) (Layout, error) {
+	t := reflect.TypeOf(record)
+	if t == nil {
+		return Layout{}, errors.New("cannot describe nil record")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	codec, err := codecFor(t)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	layout := Layout{Name: t.Name(), Fields: make([]LayoutField, len(codec.fields))}
+	for i, fc := range codec.fields {
+		kind := kindOf(t.Field(fc.index).Type)
+		tag := fc.tag
+		if kind == KindDate && tag.Format == "" {
+			tag.Format = timeFormat(tag)
+		}
+		layout.Fields[i] = LayoutField{
+			Name:     fc.name,
+			Kind:     kind,
+			Start:    tag.Start,
+			End:      tag.End,
+			Length:   tag.Length(),
+			Type:     tag.Type,
+			Format:   tag.Format,
+			Sign:     tag.Sign,
+			Decimals: tag.Decimals,
+			Charset:  tag.Charset,
+			Overflow: tag.Overflow,
+		}
+	}
+	return layout, nil
+}
+
+func kindOf(t reflect.Type) string {
+	if t == timeType {
+		return KindDate
+	}
+	ptrType := reflect.PtrTo(t)
+	if ptrType.Implements(fixedWidthMarshalerType) || ptrType.Implements(textMarshalerType) {
+		return KindString
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return KindInt
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return KindUint
+	case reflect.Float32, reflect.Float64:
+		return KindFloat
+	case reflect.Bool:
+		return KindBool
+	default:
+		return KindString
+	}
+}
+
+// LoadLayout reads a Layout from JSON, as written by Layout.JSON.
+// A field may give its length instead of its end position. The layout is validated before it is returned.
+func LoadLayout(data []byte) (Layout, error) {
+	var layout Layout
+	if err := json.Unmarshal(data, &layout); err != nil {
+		return Layout{}, errors.Wrap(err, "could not read layout JSON")
+	}
+
+	for i, f := range layout.Fields {
+		if f.Kind == "" {
+			f.Kind = KindString
+		}
+		if f.End == 0 && f.Length > 0 {
+			f.End = f.Start + f.Length - 1
+		}
+		if f.Length == 0 {
+			f.Length = f.Tag().Length()
+		}
+		if f.Length != f.Tag().Length() {
+			return Layout{}, fmt.Errorf("length of %s does not match its start and end", f.Name)
+		}
+		layout.Fields[i] = f
+	}
+	return layout, layout.Validate()
+}
+
+// Validate checks the layout the same way Register checks a record struct.
+func (l Layout) Validate() error {
+	_, err := l.compile()
+	return err
+}
+
+func (l Layout) compile() (*recordCodec, error) {
+	c := &recordCodec{fields: make([]fieldCodec, 0, len(l.Fields))}
+	names := make(map[string]bool, len(l.Fields))
+	for i, f := range l.Fields {
+		// DynamicRecord holds values by field name
+		if names[f.Name] {
+			return nil, fmt.Errorf("field name %s is used more than once", f.Name)
+		}
+		names[f.Name] = true
+
+		t, ok := kindTypes[f.Kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown kind %s for %s", f.Kind, f.Name)
+		}
+
+		tag := f.Tag()
+		if err := tag.validate(f.Name); err != nil {
+			return nil, err
+		}
+		if err := c.checkPosition(f.Name, tag); err != nil {
+			return nil, err
+		}
+
+		fc, err := compileField(f.Name, t, tag)
+		if err != nil {
+			return nil, err
+		}
+		fc.index = i
+		c.fields = append(c.fields, fc)
+		c.width = tag.End
+	}
+	return c, nil
+}
+
+var layoutHeader = []string{"Field", "Start", "End", "Length", "Type", "Format"}
+
+func (f LayoutField) row() []string {
+	return []string{
+		f.Name,
+		strconv.Itoa(f.Start),
+		strconv.Itoa(f.End),
+		strconv.Itoa(f.Length),
+		f.Type,
+		f.displayFormat(),
+	}
+}
+
+// displayFormat shows the Go layouts of a named date format, since tables are read by people who don't know the names.
+func (f LayoutField) displayFormat() string {
+	if f.Kind != KindDate || f.Format == "" {
+		return f.Format
+	}
+	return strings.Join(format.ResolveDateFormat(f.Format).Layouts, " or ")
+}
+
+// Markdown returns the layout as a Markdown table, headed by the name of the layout.
+// Named date formats are shown as their Go layouts.
+func (l Layout) Markdown() string {
+	var b strings.Builder
+	if l.Name != "" {
+		b.WriteString("## " + l.Name + "\n\n")
+	}
+
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for _, c := range cells {
+			b.WriteString(" " + strings.ReplaceAll(c, "|", `\|`) + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(layoutHeader)
+	b.WriteString("|" + strings.Repeat(" --- |", len(layoutHeader)) + "\n")
+	for _, f := range l.Fields {
+		writeRow(f.row())
+	}
+	return b.String()
+}
+
+// CSV returns the layout as a CSV table with a header row.
+func (l Layout) CSV() (string, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(layoutHeader); err != nil {
+		return "", err
+	}
+	for _, f := range l.Fields {
+		if err := w.Write(f.row()); err != nil {
+			return "", err
+		}
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
+
+// JSON returns the layout as indented JSON, which can be read back with LoadLayout.
+func (l Layout) JSON() ([]byte, error) {
+	return json.MarshalIndent(l, "", "  ")
+}
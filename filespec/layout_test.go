@@ -0,0 +1,121 @@
+package filespec
+
+import (
+	"github.com/Direct-Debit/go-commons/format"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type testLayoutRecord struct {
+	Name   string    `pos:"1-10" type:"A"`
+	Amount int       `pos:"11-20" type:"N" sign:"overpunch"`
+	Date   time.Time `pos:"21-28" type:"N"`
+	Active bool      `pos:"29-29" type:"A"`
+}
+
+func TestLayoutOf(t *testing.T) {
+	l, err := LayoutOf(testLayoutRecord{})
+	assert.NoError(t, err)
+	assert.Equal(t, "testLayoutRecord", l.Name)
+	assert.Equal(t, []LayoutField{
+		{Name: "Name", Kind: KindString, Start: 1, End: 10, Length: 10, Type: "A"},
+		{Name: "Amount", Kind: KindInt, Start: 11, End: 20, Length: 10, Type: "N", Sign: SignOverpunch},
+		{Name: "Date", Kind: KindDate, Start: 21, End: 28, Length: 8, Type: "N", Format: "20060102"},
+		{Name: "Active", Kind: KindBool, Start: 29, End: 29, Length: 1, Type: "A"},
+	}, l.Fields)
+
+	assert.Equal(t, "## testLayoutRecord\n\n"+
+		"| Field | Start | End | Length | Type | Format |\n"+
+		"| --- | --- | --- | --- | --- | --- |\n"+
+		"| Name | 1 | 10 | 10 | A |  |\n"+
+		"| Amount | 11 | 20 | 10 | N |  |\n"+
+		"| Date | 21 | 28 | 8 | N | 20060102 |\n"+
+		"| Active | 29 | 29 | 1 | A |  |\n", l.Markdown())
+
+	c, err := l.CSV()
+	assert.NoError(t, err)
+	assert.Equal(t, "Field,Start,End,Length,Type,Format\n"+
+		"Name,1,10,10,A,\n"+
+		"Amount,11,20,10,N,\n"+
+		"Date,21,28,8,N,20060102\n"+
+		"Active,29,29,1,A,\n", c)
+
+	_, err = LayoutOf(struct {
+		A string `pos:"1"`
+	}{})
+	assert.Error(t, err)
+}
+
+func TestLoadLayout(t *testing.T) {
+	l, err := LayoutOf(&testLayoutRecord{})
+	assert.NoError(t, err)
+	j, err := l.JSON()
+	assert.NoError(t, err)
+
+	loaded, err := LoadLayout(j)
+	assert.NoError(t, err)
+	assert.Equal(t, l, loaded)
+
+	loaded, err = LoadLayout([]byte(`{"name": "Short", "fields": [
+		{"name": "Ref", "start": 1, "length": 5, "type": "AN"},
+		{"name": "Amount", "kind": "int", "start": 6, "end": 12, "type": "C"}
+	]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, loaded.Fields[0].End)
+	assert.Equal(t, KindString, loaded.Fields[0].Kind)
+	assert.Equal(t, 7, loaded.Fields[1].Length)
+
+	for _, invalid := range []string{
+		`{"fields": [{"name": "A", "start": 1, "end": 5}, {"name": "B", "start": 5, "end": 8}]}`,
+		`{"fields": [{"name": "A", "start": 1, "end": 5, "length": 4}]}`,
+		`{"fields": [{"name": "A", "kind": "complex", "start": 1, "end": 5}]}`,
+		`{"fields": [{"name": "A", "start": 1, "end": 5, "sign": "maybe"}]}`,
+		`{"fields": [`,
+	} {
+		_, err = LoadLayout([]byte(invalid))
+		assert.Error(t, err, invalid)
+	}
+}
+
+type testNamedLayoutRecord struct {
+	Action time.Time `pos:"1-10" format:"filespec-layout-test"`
+	Ref    string    `pos:"11-15"`
+}
+
+func TestLayoutShowsNamedFormatLayouts(t *testing.T) {
+	assert.NoError(t, format.RegisterDateFormat("filespec-layout-test", format.DateFormat{
+		Layouts: []string{format.DDsMMsYYYY, format.DateShortDashes},
+	}))
+	l, err := LayoutOf(testNamedLayoutRecord{})
+	assert.NoError(t, err)
+	assert.Equal(t, "filespec-layout-test", l.Fields[0].Format, "JSON keeps the name, with all its layouts")
+
+	c, err := l.CSV()
+	assert.NoError(t, err)
+	assert.Equal(t, "Field,Start,End,Length,Type,Format\n"+
+		"Action,1,10,10,,02/01/2006 or 2006-01-02\n"+
+		"Ref,11,15,5,,\n", c)
+}
+
+func TestLayoutDateLengthNeedsFormat(t *testing.T) {
+	_, err := LayoutOf(struct {
+		Date time.Time `pos:"1-12" type:"N"`
+	}{})
+	assert.Error(t, err)
+
+	_, err = LoadLayout([]byte(`{"fields": [{"name": "Date", "kind": "date", "start": 1, "length": 12}]}`))
+	assert.Error(t, err)
+
+	l, err := LoadLayout([]byte(`{"fields": [{"name": "Date", "kind": "date", "start": 1, "length": 12, "format": "DateTimeCompact"}]}`))
+	assert.NoError(t, err)
+	j, err := l.JSON()
+	assert.NoError(t, err)
+	_, err = LoadLayout(j)
+	assert.NoError(t, err, "exported layouts load again")
+}
+
+func TestLayoutDuplicateNames(t *testing.T) {
+	_, err := LoadLayout([]byte(`{"fields": [{"name": "A", "start": 1, "end": 5}, {"name": "A", "start": 6, "end": 8}]}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,264 @@
+package filespec
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Values for the sign tag option of numeric fields.
+// Without a sign option a numeric field is unsigned, and generating a negative value is an error.
+const (
+	// SignLeading writes a '+' or '-' in an extra character at the start of the field.
+	SignLeading = "leading"
+	// SignTrailing writes a '+' or '-' in an extra character at the end of the field.
+	SignTrailing = "trailing"
+	// SignOverpunch encodes the sign in the last digit, as in COBOL zoned decimals (e.g. "1234}" for -12340).
+	SignOverpunch = "overpunch"
+	// SignLeadingOverpunch encodes the sign in the first digit.
+	SignLeadingOverpunch = "leading-overpunch"
+)
+
+const (
+	overpunchPositive = "{ABCDEFGHI"
+	overpunchNegative = "}JKLMNOPQR"
+)
+
+func validSign(sign string) bool {
+	switch sign {
+	case "", SignLeading, SignTrailing, SignOverpunch, SignLeadingOverpunch:
+		return true
+	}
+	return false
+}
+
+// scale returns the number of decimal places in the field.
+// Currency fields (type "C") default to two decimal places.
+func (r RecordTag) scale() int {
+	if r.Decimals == 0 && r.Type == "C" {
+		return 2
+	}
+	return r.Decimals
+}
+
+// numeric reports whether an integer field needs the numeric encoding rather than plain digits.
+func (r RecordTag) numeric() bool {
+	return r.Sign != "" || r.Decimals > 0 || r.Type == "C"
+}
+
+// formatNumber writes the magnitude (in units of the field's scale) and sign of a number as a full-width field.
+// Delimited columns have no width, so they are not zero padded.
+// Currency fields get an explicit decimal point, other fields have an implied decimal point.
+func formatNumber(neg bool, mag uint64, tag RecordTag) (string, error) {
+	digits := strconv.FormatUint(mag, 10)
+	if scale := tag.scale(); tag.Type == "C" && scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+
+	if width := tag.Length(); width > 0 {
+		if tag.Sign == SignLeading || tag.Sign == SignTrailing {
+			width--
+		}
+		if len(digits) > width {
+			return "", fmt.Errorf("%s does not fit in %d characters", digits, width)
+		}
+		digits = strings.Repeat("0", width-len(digits)) + digits
+	}
+
+	signChar := "+"
+	if neg && mag != 0 {
+		signChar = "-"
+	} else {
+		neg = false
+	}
+
+	switch tag.Sign {
+	case "":
+		if neg {
+			return "", fmt.Errorf("negative value -%s in unsigned field", strings.TrimLeft(digits, "0"))
+		}
+		return digits, nil
+	case SignLeading:
+		return signChar + digits, nil
+	case SignTrailing:
+		return digits + signChar, nil
+	case SignOverpunch:
+		last := len(digits) - 1
+		return digits[:last] + overpunch(digits[last], neg), nil
+	case SignLeadingOverpunch:
+		return overpunch(digits[0], neg) + digits[1:], nil
+	}
+	return "", fmt.Errorf("unknown sign option %s", tag.Sign)
+}
+
+func overpunch(digit byte, neg bool) string {
+	if neg {
+		return string(overpunchNegative[digit-'0'])
+	}
+	return string(overpunchPositive[digit-'0'])
+}
+
+func unOverpunch(c byte) (digit byte, neg bool, err error) {
+	if c >= '0' && c <= '9' {
+		return c, false, nil
+	}
+	if i := strings.IndexByte(overpunchPositive, c); i >= 0 {
+		return '0' + byte(i), false, nil
+	}
+	if i := strings.IndexByte(overpunchNegative, c); i >= 0 {
+		return '0' + byte(i), true, nil
+	}
+	return 0, false, fmt.Errorf("invalid overpunch character %c", c)
+}
+
+func signFromChar(c byte) (neg bool, err error) {
+	switch c {
+	case '-':
+		return true, nil
+	case '+', ' ':
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid sign character %c", c)
+}
+
+// parseNumber reads the sign and magnitude (in units of the field's scale) of a numeric field.
+// If the value has a decimal point it is honoured, otherwise it has an implied decimal point,
+// so "00001234" in a currency field is 1234 cents. A blank field is zero.
+func parseNumber(strVal string, tag RecordTag) (neg bool, mag uint64, err error) {
+	s := strVal
+	if len(strings.TrimSpace(s)) == 0 {
+		return false, 0, nil
+	}
+
+	switch tag.Sign {
+	case SignLeading:
+		neg, err = signFromChar(s[0])
+		s = s[1:]
+	case SignTrailing:
+		neg, err = signFromChar(s[len(s)-1])
+		s = s[:len(s)-1]
+	case SignOverpunch:
+		s = strings.TrimRight(s, " ")
+		var d byte
+		d, neg, err = unOverpunch(s[len(s)-1])
+		s = s[:len(s)-1] + string(d)
+	case SignLeadingOverpunch:
+		s = strings.TrimLeft(s, " ")
+		var d byte
+		d, neg, err = unOverpunch(s[0])
+		s = string(d) + s[1:]
+	default:
+		s = strings.TrimSpace(s)
+		if strings.HasPrefix(s, "-") {
+			neg = true
+		}
+		s = strings.TrimLeft(s, "+-")
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	s = strings.TrimSpace(s)
+
+	scale := tag.scale()
+	if whole, frac, hasPoint := strings.Cut(s, "."); hasPoint {
+		if len(frac) > scale {
+			return false, 0, fmt.Errorf("%s has more than %d decimal places", s, scale)
+		}
+		s = whole + frac + strings.Repeat("0", scale-len(frac))
+	}
+
+	mag, err = strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return false, 0, errors.Wrapf(err, "could not parse number %s", strVal)
+	}
+	return neg, mag, nil
+}
+
+func parseSignedNumber(field reflect.Value, strVal string, tag RecordTag) error {
+	neg, mag, err := parseNumber(strVal, tag)
+	if err != nil {
+		return err
+	}
+	if mag > math.MaxInt64 && !(neg && mag == math.MaxInt64+1) {
+		return fmt.Errorf("%s overflows int64", strVal)
+	}
+
+	v := int64(mag)
+	if neg {
+		v = -v
+	}
+	if field.OverflowInt(v) {
+		return fmt.Errorf("%s overflows %v", strVal, field.Type())
+	}
+	field.SetInt(v)
+	return nil
+}
+
+func parseUnsignedNumber(field reflect.Value, strVal string, tag RecordTag) error {
+	neg, mag, err := parseNumber(strVal, tag)
+	if err != nil {
+		return err
+	}
+	if neg && mag != 0 {
+		return fmt.Errorf("negative value %s for %v", strVal, field.Type())
+	}
+	if field.OverflowUint(mag) {
+		return fmt.Errorf("%s overflows %v", strVal, field.Type())
+	}
+	field.SetUint(mag)
+	return nil
+}
+
+func parseFloatNumber(field reflect.Value, strVal string, tag RecordTag) error {
+	neg, mag, err := parseNumber(strVal, tag)
+	if err != nil {
+		return err
+	}
+	v := float64(mag) / math.Pow10(tag.scale())
+	if neg {
+		v = -v
+	}
+	field.SetFloat(v)
+	return nil
+}
+
+func signedNumberToStr(val reflect.Value, tag RecordTag) (string, error) {
+	v := val.Int()
+	mag := uint64(v)
+	if v < 0 {
+		mag = uint64(-(v + 1)) + 1 // avoids overflow for math.MinInt64
+	}
+	return formatNumber(v < 0, mag, tag)
+}
+
+func unsignedNumberToStr(val reflect.Value, tag RecordTag) (string, error) {
+	return formatNumber(false, val.Uint(), tag)
+}
+
+func floatNumberToStr(val reflect.Value, tag RecordTag) (string, error) {
+	v := val.Float()
+	decimals := floatDecimals(v, val.Type().Bits())
+	if decimals > tag.scale() && tag.Overflow == OverflowError {
+		return "", fmt.Errorf("%v has %d decimals, but the field only holds %d", v, decimals, tag.scale())
+	}
+	scaled := math.Round(math.Abs(v) * math.Pow10(tag.scale()))
+	if math.IsNaN(scaled) || scaled >= math.MaxUint64 {
+		return "", fmt.Errorf("%v can not be written as a number", v)
+	}
+	return formatNumber(v < 0, uint64(scaled), tag)
+}
+
+// floatDecimals returns the number of decimals in the shortest decimal form of v, so 12.34 has 2.
+func floatDecimals(v float64, bits int) int {
+	s := strconv.FormatFloat(v, 'f', -1, bits)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
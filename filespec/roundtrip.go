@@ -0,0 +1,260 @@
+package filespec
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// TestingT is the part of testing.TB that CheckRoundTrip uses.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+	Helper()
+}
+
+// RoundTripOptions configure CheckRoundTrip and FindRoundTripMismatches.
+type RoundTripOptions struct {
+	// Count is the number of random records to check, defaults to 100.
+	Count int
+	// Seed for the random values. If zero, a time based seed is used. Mismatches report the seed used.
+	Seed int64
+	// Generators create random values for fields, by field name.
+	// Fields with custom codecs are left at their zero value unless they have a generator.
+	Generators map[string]func(r *rand.Rand) interface{}
+}
+
+// Mismatch is a field that did not survive a round trip through GenerateLine and ParseRecord.
+type Mismatch struct {
+	Seed      int64
+	Record    int
+	Field     string
+	Generated interface{}
+	Parsed    interface{}
+	Line      string
+	Err       error // Set if generating or parsing the record failed
+}
+
+func (m Mismatch) String() string {
+	if m.Err != nil {
+		return fmt.Sprintf("seed %d, record %d: %v", m.Seed, m.Record, m.Err)
+	}
+	return fmt.Sprintf("seed %d, record %d, field %s: generated %#v, but parsed %#v from %q",
+		m.Seed, m.Record, m.Field, m.Generated, m.Parsed, m.Line)
+}
+
+// CheckRoundTrip generates random valid values for the record type of record (a struct or pointer to one),
+// and reports every field that isn't the same after GenerateLine and then ParseRecord to t.
+// It returns true if all the records survived the round trip.
+//
+// Random values are valid for the field's tags: alphanumeric strings fill the whole field,
+// and dates have no more precision than their layout. Numeric strings can start with zeros,
+// which ParseRecord trims, so give "N" string fields whose values never start with zero a Generator.
+func CheckRoundTrip(t TestingT, record interface{}, opts RoundTripOptions) bool {
+	t.Helper()
+	mismatches, err := FindRoundTripMismatches(record, opts)
+	if err != nil {
+		t.Errorf("could not check round trip: %v", err)
+		return false
+	}
+	for _, m := range mismatches {
+		t.Errorf("round trip mismatch: %s", m)
+	}
+	return len(mismatches) == 0
+}
+
+// FindRoundTripMismatches does the same checks as CheckRoundTrip, and returns the mismatches.
+func FindRoundTripMismatches(record interface{}, opts RoundTripOptions) ([]Mismatch, error) {
+	t := reflect.TypeOf(record)
+	if t == nil {
+		return nil, errors.New("cannot check nil record")
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	codec, err := codecFor(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Count <= 0 {
+		opts.Count = 100
+	}
+	if opts.Seed == 0 {
+		opts.Seed = time.Now().UnixNano()
+	}
+	rnd := rand.New(rand.NewSource(opts.Seed))
+
+	var mismatches []Mismatch
+	for i := 0; i < opts.Count; i++ {
+		generated := reflect.New(t).Elem()
+		for _, fc := range codec.fields {
+			field := generated.Field(fc.index)
+			if gen, ok := opts.Generators[fc.name]; ok {
+				v := reflect.ValueOf(gen(rnd))
+				if !generatedFits(v, field.Type()) {
+					return nil, fmt.Errorf("generator for %s returned %#v, which is not a %v", fc.name, v, field.Type())
+				}
+				field.Set(v.Convert(field.Type()))
+			} else {
+				fc.randomize(rnd, field)
+			}
+		}
+
+		var b strings.Builder
+		parsed := reflect.New(t).Elem()
+		err := codec.generate(generated, &b)
+		line := strings.TrimSuffix(b.String(), "\n")
+		if err == nil {
+			err = codec.parse(line, parsed)
+		}
+		if err != nil {
+			mismatches = append(mismatches, Mismatch{Seed: opts.Seed, Record: i, Line: line, Err: err})
+			continue
+		}
+
+		for _, fc := range codec.fields {
+			g, p := generated.Field(fc.index).Interface(), parsed.Field(fc.index).Interface()
+			if !valuesEqual(g, p) {
+				mismatches = append(mismatches, Mismatch{
+					Seed: opts.Seed, Record: i, Field: fc.name, Generated: g, Parsed: p, Line: line,
+				})
+			}
+		}
+	}
+	return mismatches, nil
+}
+
+// generatedFits reports whether a generated value can be converted to the field's type without changing its meaning.
+// Go converts integers to strings as runes, so only strings fill string fields.
+func generatedFits(v reflect.Value, t reflect.Type) bool {
+	if !v.IsValid() || !v.Type().ConvertibleTo(t) {
+		return false
+	}
+	return t.Kind() != reflect.String || v.Kind() == reflect.String
+}
+
+func valuesEqual(a, b interface{}) bool {
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// randomize sets field to a random value that is valid for the field's tags.
+// Fields with custom codecs or unsupported types are left unchanged.
+func (fc fieldCodec) randomize(rnd *rand.Rand, field reflect.Value) {
+	t := field.Type()
+	ptrType := reflect.PtrTo(t)
+	if t != timeType && (ptrType.Implements(fixedWidthUnmarshalerType) || ptrType.Implements(textUnmarshalerType)) {
+		return
+	}
+
+	switch {
+	case t == timeType:
+		field.Set(reflect.ValueOf(fc.randomDate(rnd)))
+	case t.Kind() == reflect.String:
+		field.SetString(fc.randomString(rnd))
+	case t.Kind() == reflect.Bool:
+		field.SetBool(rnd.Intn(2) == 1)
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		limit := uint64(1)<<(t.Bits()-1) - 1
+		v := int64(fc.randomMagnitude(rnd, limit))
+		if fc.tag.Sign != "" && rnd.Intn(2) == 1 {
+			v = -v
+		}
+		field.SetInt(v)
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		limit := uint64(math.MaxUint64) >> (64 - t.Bits())
+		field.SetUint(fc.randomMagnitude(rnd, limit))
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		// Stay within the integers a float can represent exactly
+		limit := uint64(1e15)
+		if t.Kind() == reflect.Float32 {
+			limit = 1e6
+		}
+		v := float64(fc.randomMagnitude(rnd, limit)) / math.Pow10(fc.tag.scale())
+		if fc.tag.Sign != "" && rnd.Intn(2) == 1 {
+			v = -v
+		}
+		field.SetFloat(v)
+	}
+}
+
+// digitCapacity is the number of digits that fit in a numeric field, after making space for a sign and decimal point.
+func (fc fieldCodec) digitCapacity() int {
+	n := fc.tag.Length()
+	if fc.tag.Sign == SignLeading || fc.tag.Sign == SignTrailing {
+		n--
+	}
+	if fc.tag.Type == "C" && fc.tag.scale() > 0 {
+		n--
+	}
+	return n
+}
+
+// randomMagnitude returns a random number, with a random number of digits that fit in the field, of at most limit.
+func (fc fieldCodec) randomMagnitude(rnd *rand.Rand, limit uint64) uint64 {
+	digits := fc.digitCapacity()
+	if digits <= 0 {
+		return 0
+	}
+	if digits > 19 {
+		digits = 19
+	}
+	max := uint64(1)
+	for i := rnd.Intn(digits) + 1; i > 0; i-- {
+		max *= 10
+	}
+	v := rnd.Uint64() % max
+	if v > limit {
+		v %= limit + 1
+	}
+	return v
+}
+
+func (fc fieldCodec) randomString(rnd *rand.Rand) string {
+	length := fc.tag.Length()
+	if fc.tag.Type == "N" {
+		// Leading zeros are generated too, since GenerateLine keeps them and ParseRecord trims them
+		result := make([]byte, rnd.Intn(length)+1)
+		for i := range result {
+			result[i] = digits[rnd.Intn(len(digits))]
+		}
+		return string(result)
+	}
+
+	chars := fc.allowed
+	if chars == "" {
+		chars = letters + digits + " "
+	}
+	allowed := []rune(chars)
+	if fc.tag.Type == "A" || fc.tag.Type == "AN" {
+		// Generated values are upper-cased, so lowercase characters can't survive the round trip
+		allowed = []rune(strings.ToUpper(chars))
+	}
+
+	// Fill the whole field, since ParseRecord does not trim the padding
+	result := make([]rune, length)
+	for i := range result {
+		result[i] = allowed[rnd.Intn(len(allowed))]
+	}
+	return string(result)
+}
+
+// randomDate returns a random time between 1970 and 2068 with only as much precision as the field's layout keeps.
+func (fc fieldCodec) randomDate(rnd *rand.Rand) time.Time {
+	start := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	t := start.Add(time.Duration(rnd.Int63n(int64(99 * 365 * 24 * time.Hour))))
+
+	layout := timeFormat(fc.tag)
+	canonical, err := time.Parse(layout, t.Format(layout))
+	if err != nil {
+		return t
+	}
+	return canonical
+}
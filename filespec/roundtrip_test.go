@@ -0,0 +1,88 @@
+package filespec
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripRecord struct {
+	Name      string            `pos:"1-10" type:"A"`
+	Reference string            `pos:"11-16" type:"N"`
+	Account   testAccountNumber `pos:"17-27" type:"N"`
+	Amount    int64             `pos:"28-36" type:"N" sign:"leading"`
+	Balance   int               `pos:"37-44" type:"N" sign:"overpunch"`
+	Count     uint16            `pos:"45-49" type:"N"`
+	Rate      float64           `pos:"50-55" type:"C" decimals:"3"`
+	Date      time.Time         `pos:"56-63" type:"N"`
+	Due       time.Time         `pos:"64-73" type:"AN"`
+	Expiry    time.Time         `pos:"74-77" type:"N"`
+	Active    bool              `pos:"78-78" type:"A"`
+	Payee     string            `pos:"79-88" type:"AN" charset:"bank"`
+}
+
+func TestCheckRoundTrip(t *testing.T) {
+	assert.True(t, CheckRoundTrip(t, roundTripRecord{}, RoundTripOptions{
+		Count: 500,
+		Generators: map[string]func(r *rand.Rand) interface{}{
+			"Account":   func(r *rand.Rand) interface{} { return testAccountNumber("62123456789") },
+			"Reference": func(r *rand.Rand) interface{} { return fmt.Sprint(r.Intn(99999) + 1) },
+		},
+	}))
+}
+
+func TestRoundTripFindsTrimmedZeros(t *testing.T) {
+	mismatches, err := FindRoundTripMismatches(struct {
+		Reference string `pos:"1-6" type:"N"`
+	}{}, RoundTripOptions{Count: 200, Seed: 7})
+	assert.NoError(t, err)
+	if assert.NotEmpty(t, mismatches, "numeric strings with leading zeros don't survive ParseRecord") {
+		m := mismatches[0]
+		assert.Equal(t, "Reference", m.Field)
+		assert.Equal(t, strings.TrimLeft(m.Generated.(string), "0"), m.Parsed)
+	}
+}
+
+func TestRoundTripGeneratorType(t *testing.T) {
+	for _, v := range []interface{}{42, nil, time.Now()} {
+		_, err := FindRoundTripMismatches(roundTripMismatchRecord{}, RoundTripOptions{
+			Generators: map[string]func(r *rand.Rand) interface{}{
+				"Name": func(r *rand.Rand) interface{} { return v },
+			},
+		})
+		assert.Error(t, err, "%#v", v)
+	}
+}
+
+type roundTripMismatchRecord struct {
+	Account testAccountNumber `pos:"1-11" type:"N"`
+	Name    string            `pos:"12-21" type:"A"`
+}
+
+func TestFindRoundTripMismatches(t *testing.T) {
+	mismatches, err := FindRoundTripMismatches(&roundTripMismatchRecord{}, RoundTripOptions{
+		Count: 3,
+		Seed:  42,
+		Generators: map[string]func(r *rand.Rand) interface{}{
+			// The account number is zero-padded when generated, and kept that way when parsed
+			"Account": func(r *rand.Rand) interface{} { return "0123" },
+		},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, mismatches, 3)
+	for i, m := range mismatches {
+		assert.Equal(t, int64(42), m.Seed)
+		assert.Equal(t, i, m.Record)
+		assert.Equal(t, "Account", m.Field)
+		assert.Equal(t, testAccountNumber("0123"), m.Generated)
+		assert.Equal(t, testAccountNumber("00000000123"), m.Parsed)
+	}
+
+	_, err = FindRoundTripMismatches(struct {
+		Name string `pos:"1-0"`
+	}{}, RoundTripOptions{})
+	assert.Error(t, err)
+}
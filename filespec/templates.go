@@ -4,18 +4,198 @@ import (
 	"fmt"
 	"github.com/Direct-Debit/go-commons/errlib"
 	"github.com/Direct-Debit/go-commons/fileio"
+	"github.com/Direct-Debit/go-commons/format"
+	"github.com/pkg/errors"
+	"reflect"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 )
 
-func ProcessTemplate(location string, data interface{}) string {
-	storage := fileio.CurrStorage()
-	tmpContent, err := storage.Load(location)
-	errlib.PanicError(err, fmt.Sprintf("Couldn't load %v template", location))
+// DefaultTemplateCheckInterval is how long a TemplateEngine uses a cached template from a remote store,
+// like an SFTP or S3 store, before it checks whether the file changed.
+const DefaultTemplateCheckInterval = time.Minute
+
+// TemplateEngine loads text templates from a FileStore, and caches them until the template file is modified.
+// It is safe for concurrent use.
+type TemplateEngine struct {
+	// Store to load templates from. If nil, fileio.CurrStorage() is used.
+	Store fileio.FileStore
+	// Funcs are added to TemplateFuncs, overriding functions with the same name.
+	Funcs template.FuncMap
+	// CheckInterval is how long a cached template is used before the store is asked whether the file changed.
+	// If zero, local stores are checked every time, since that is cheap,
+	// and other stores every DefaultTemplateCheckInterval, since every check can cost a connection.
+	CheckInterval time.Duration
+
+	lock  sync.Mutex
+	cache map[templateKey]cachedTemplate
+}
+
+// templateKey identifies a template by its store too, since the store can change with fileio.SetStorage.
+type templateKey struct {
+	store    fileio.FileStore
+	location string
+}
+
+type cachedTemplate struct {
+	tmpl      *template.Template
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+var defaultEngine = &TemplateEngine{}
+
+// NewTemplateEngine creates a TemplateEngine that loads templates from store.
+func NewTemplateEngine(store fileio.FileStore) *TemplateEngine {
+	return &TemplateEngine{Store: store}
+}
+
+func (e *TemplateEngine) store() fileio.FileStore {
+	if e.Store == nil {
+		return fileio.CurrStorage()
+	}
+	return e.Store
+}
+
+func (e *TemplateEngine) checkInterval(store fileio.FileStore) time.Duration {
+	if e.CheckInterval != 0 {
+		return e.CheckInterval
+	}
+	switch store.(type) {
+	case fileio.SimpleFileStore, *fileio.SimpleFileStore:
+		return 0
+	default:
+		return DefaultTemplateCheckInterval
+	}
+}
+
+// Template returns the parsed template at location.
+// The cached template is reused until CheckInterval has passed, and after that as long as the modification time
+// of the file stays the same. Templates in stores that don't report modification times are parsed again
+// after every CheckInterval.
+func (e *TemplateEngine) Template(location string) (*template.Template, error) {
+	store := e.store()
+	// Stores that can't be map keys are not cached
+	cacheable := reflect.TypeOf(store).Comparable()
+	key := templateKey{store: store, location: location}
+	interval := e.checkInterval(store)
+
+	var cached cachedTemplate
+	var ok bool
+	if cacheable {
+		e.lock.Lock()
+		cached, ok = e.cache[key]
+		e.lock.Unlock()
+	}
+	if ok && interval > 0 && time.Since(cached.checkedAt) < interval {
+		return cached.tmpl, nil
+	}
+
+	info, err := store.GetInfo(location)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get info for %s template", location)
+	}
+	checkedAt := time.Now()
+	tmpl := cached.tmpl
+	if !ok || info.ModTime.IsZero() || !cached.modTime.Equal(info.ModTime) {
+		content, err := store.Load(location)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not load %s template", location)
+		}
+		tmpl, err = template.New(location).Funcs(TemplateFuncs()).Funcs(e.Funcs).Parse(content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse %s template", location)
+		}
+	}
+
+	if cacheable && (interval > 0 || !info.ModTime.IsZero()) {
+		e.lock.Lock()
+		if e.cache == nil {
+			e.cache = make(map[templateKey]cachedTemplate)
+		}
+		e.cache[key] = cachedTemplate{tmpl: tmpl, modTime: info.ModTime, checkedAt: checkedAt}
+		e.lock.Unlock()
+	}
+	return tmpl, nil
+}
+
+// Execute applies the template at location to data and returns the result.
+func (e *TemplateEngine) Execute(location string, data interface{}) (string, error) {
+	tmpl, err := e.Template(location)
+	if err != nil {
+		return "", err
+	}
 
-	tmp, err := template.New(location).Parse(tmpContent)
-	errlib.PanicError(err, fmt.Sprintf("Couldn't parse %v template", location))
 	var builder strings.Builder
-	errlib.PanicError(tmp.Execute(&builder, data), fmt.Sprintf("Couldn't write %v template", location))
-	return builder.String()
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", errors.Wrapf(err, "could not write %s template", location)
+	}
+	return builder.String(), nil
+}
+
+// Invalidate removes the template at location from the cache, or all templates if location is empty.
+func (e *TemplateEngine) Invalidate(location string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if location == "" {
+		e.cache = nil
+		return
+	}
+	for key := range e.cache {
+		if key.location == location {
+			delete(e.cache, key)
+		}
+	}
+}
+
+// Deprecated: ProcessTemplate panics on any error, rather use TemplateEngine.Execute.
+func ProcessTemplate(location string, data interface{}) string {
+	result, err := defaultEngine.Execute(location, data)
+	errlib.PanicError(err, fmt.Sprintf("Couldn't process %v template", location))
+	return result
+}
+
+// TemplateFuncs returns the functions available in templates processed by a TemplateEngine:
+//   - centToCommaRand: Formats cents as rands with a decimal comma, see format.CentToCommaRand.
+//   - formatDate: Formats a time with a layout, or the name of a format registered with format.RegisterDateFormat,
+//     including the layout constants in the format package. For example {{ formatDate .ActionDate "DateShort8" }}.
+//   - padLeft, padRight: Pad a value to a width with a pad character, e.g. {{ padLeft .Ref 10 "0" }}.
+//   - base36: Converts an int to base 36, see format.IntToBase36.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"centToCommaRand": format.CentToCommaRand,
+		"formatDate":      formatDate,
+		"padLeft":         padLeft,
+		"padRight":        padRight,
+		"base36":          format.IntToBase36,
+	}
+}
+
+func formatDate(t time.Time, layout string) string {
+	return format.ResolveDateFormat(layout).Format(t)
+}
+
+func padding(value interface{}, width int, pad string) (string, string) {
+	s := fmt.Sprint(value)
+	if pad == "" {
+		pad = " "
+	}
+	missing := width - len([]rune(s))
+	if missing <= 0 {
+		return s, ""
+	}
+	// The padding is cut by runes, so a multi-byte or multi-character pad still makes the value width characters
+	return s, string([]rune(strings.Repeat(pad, missing))[:missing])
+}
+
+func padLeft(value interface{}, width int, pad string) string {
+	s, p := padding(value, width, pad)
+	return p + s
+}
+
+func padRight(value interface{}, width int, pad string) string {
+	s, p := padding(value, width, pad)
+	return s + p
 }
@@ -0,0 +1,132 @@
+package filespec
+
+import (
+	"github.com/Direct-Debit/go-commons/fileio"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestTemplateEngine(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "letter.tmpl")
+	write := func(content string, modTime time.Time) {
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+		assert.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	engine := NewTemplateEngine(fileio.SimpleFileStore{BasePath: dir})
+	engine.Funcs = template.FuncMap{"shout": strings.ToUpper}
+
+	write(`{{ padLeft .Ref 6 "0" }}|{{ padRight .Name 5 "" }}|{{ centToCommaRand .Cents }}|`+
+		`{{ formatDate .Date "DateShort8" }}|{{ formatDate .Date "02 Jan" }}|{{ base36 .Seq }}|{{ shout .Name }}`,
+		time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	data := map[string]interface{}{
+		"Ref":   42,
+		"Name":  "Jan",
+		"Cents": 12345,
+		"Date":  time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+		"Seq":   1322,
+	}
+	result, err := engine.Execute("letter.tmpl", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "000042|Jan  |123,45|20230201|01 Feb|10Q|JAN", result)
+
+	// The cached template is used until the modification time changes
+	write("changed", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC))
+	result, err = engine.Execute("letter.tmpl", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "000042|Jan  |123,45|20230201|01 Feb|10Q|JAN", result)
+
+	write("changed", time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC))
+	result, err = engine.Execute("letter.tmpl", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", result)
+
+	write("{{ .Missing.Field }}", time.Date(2023, 1, 3, 0, 0, 0, 0, time.UTC))
+	_, err = engine.Execute("letter.tmpl", 7)
+	assert.Error(t, err)
+
+	write("{{ .Broken ", time.Date(2023, 1, 4, 0, 0, 0, 0, time.UTC))
+	_, err = engine.Execute("letter.tmpl", data)
+	assert.Error(t, err)
+
+	_, err = engine.Execute("missing.tmpl", data)
+	assert.Error(t, err)
+}
+
+func TestTemplateEngineKeysByStore(t *testing.T) {
+	first, second := fileio.SimpleFileStore{BasePath: t.TempDir()}, fileio.SimpleFileStore{BasePath: t.TempDir()}
+	assert.NoError(t, first.Save("t.tmpl", "first"))
+	assert.NoError(t, second.Save("t.tmpl", "second"))
+	modTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, dir := range []string{first.BasePath, second.BasePath} {
+		assert.NoError(t, os.Chtimes(filepath.Join(dir, "t.tmpl"), modTime, modTime))
+	}
+
+	old := fileio.CurrStorage()
+	defer fileio.SetStorage(old)
+	engine := &TemplateEngine{}
+
+	fileio.SetStorage(first)
+	result, err := engine.Execute("t.tmpl", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", result)
+
+	fileio.SetStorage(second)
+	result, err = engine.Execute("t.tmpl", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", result, "a template with the same path in another store is not shared")
+}
+
+// countingStore is a stand-in for a remote store, counting the calls that would each cost a connection.
+type countingStore struct {
+	fileio.SimpleFileStore
+	infos, loads int
+}
+
+func (c *countingStore) GetInfo(path string) (fileio.FileInfo, error) {
+	c.infos++
+	return c.SimpleFileStore.GetInfo(path)
+}
+
+func (c *countingStore) Load(path string) (string, error) {
+	c.loads++
+	return c.SimpleFileStore.Load(path)
+}
+
+func TestTemplateEngineCheckInterval(t *testing.T) {
+	store := &countingStore{SimpleFileStore: fileio.SimpleFileStore{BasePath: t.TempDir()}}
+	assert.NoError(t, store.Save("t.tmpl", "v1"))
+	engine := NewTemplateEngine(store)
+
+	for i := 0; i < 3; i++ {
+		result, err := engine.Execute("t.tmpl", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "v1", result)
+	}
+	assert.Equal(t, 1, store.infos, "remote stores are not checked on every render")
+	assert.Equal(t, 1, store.loads)
+
+	engine.CheckInterval = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	_, err := engine.Execute("t.tmpl", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, store.infos)
+	assert.Equal(t, 1, store.loads, "an unchanged template is not loaded again")
+
+	engine.Invalidate("t.tmpl")
+	_, err = engine.Execute("t.tmpl", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, store.loads)
+}
+
+func TestPadRunes(t *testing.T) {
+	assert.Equal(t, "··ab", padLeft("ab", 4, "·"))
+	assert.Equal(t, "é-*-*", padRight("é", 5, "-*"))
+	assert.Equal(t, "abc", padLeft("abc", 2, "0"))
+}
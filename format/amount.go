@@ -0,0 +1,258 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Direct-Debit/go-commons/stdext"
+	"math"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("not a valid amount")
+	ErrAmbiguousAmount  = errors.New("separator could be a decimal or grouping separator")
+	ErrTooManyDecimals  = errors.New("more decimals than the currency allows")
+	ErrAmountOutOfRange = errors.New("amount is out of range")
+	ErrInvalidGrouping  = errors.New("grouping separators are not in groups of three digits")
+)
+
+// AmountError is returned when an amount can't be parsed. Err is one of the ErrXxx errors in this package.
+type AmountError struct {
+	Amount string
+	Err    error
+}
+
+func (e *AmountError) Error() string {
+	return fmt.Sprintf("could not parse amount %q: %v", e.Amount, e.Err)
+}
+
+func (e *AmountError) Unwrap() error {
+	return e.Err
+}
+
+// AmountStyle defines the separators accepted when parsing amounts.
+type AmountStyle struct {
+	// Decimal is the decimal separator. If zero, the decimal separator is inferred from '.' and ',', see AmountAuto.
+	Decimal rune
+	// Group holds the characters accepted as grouping separators.
+	Group string
+	// Currency of amounts without a currency symbol or code, defaults to stdext.DefaultCurrency.
+	Currency string
+}
+
+var (
+	// AmountZA is the South African convention: a decimal comma and spaces between groups, e.g. "R 1 234,56".
+	AmountZA = AmountStyle{Decimal: ',', Group: " \u00a0"}
+	// AmountInternational uses a decimal point and commas, spaces or apostrophes between groups, e.g. "1,234.56".
+	AmountInternational = AmountStyle{Decimal: '.', Group: ", \u00a0'"}
+	// AmountAuto accepts both conventions.
+	// If an amount has both '.' and ',' the last one is the decimal separator,
+	// and a separator that appears more than once is a grouping separator.
+	// A single '.' or ',' followed by exactly three digits, like "1,234", is ambiguous and refused.
+	AmountAuto = AmountStyle{Group: " \u00a0'"}
+)
+
+// ParseAmount parses a human-written amount, like "R 1 234,56", "-1,234.50 ZAR" or "(12.5)", into Money.
+// The amount may have a currency symbol or ISO code before or after it, and be negative with a leading
+// or trailing minus sign or parentheses. Amounts with fewer decimals than the currency's minor unit are
+// padded, so "R 12.5" is 1250 cents, but more decimals are refused rather than rounded.
+func ParseAmount(amount string, style AmountStyle) (stdext.Money, error) {
+	fail := func(err error) (stdext.Money, error) {
+		return stdext.Money{}, &AmountError{Amount: amount, Err: err}
+	}
+
+	s := strings.TrimSpace(amount)
+	neg := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		neg = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	// The sign may be on either side of the currency
+	s, signed := trimSign(s)
+	s, currency := trimCurrency(s)
+	if !signed {
+		s, signed = trimSign(s)
+	}
+	if signed {
+		if neg {
+			return fail(ErrInvalidAmount)
+		}
+		neg = true
+	}
+	if currency == "" {
+		currency = style.Currency
+	}
+	if currency == "" {
+		currency = stdext.DefaultCurrency
+	}
+
+	whole, fraction, err := splitAmount(s, style)
+	if err != nil {
+		return fail(err)
+	}
+	digits := stdext.CurrencyDigits(currency)
+	if len(fraction) > digits {
+		return fail(ErrTooManyDecimals)
+	}
+	fraction += strings.Repeat("0", digits-len(fraction))
+
+	var minor int64
+	for _, d := range whole + fraction {
+		if minor > (math.MaxInt64-int64(d-'0'))/10 {
+			return fail(ErrAmountOutOfRange)
+		}
+		minor = minor*10 + int64(d-'0')
+	}
+	if neg {
+		minor = -minor
+	}
+	return stdext.NewMoney(minor, currency), nil
+}
+
+// ParseZAR parses an amount with AmountAuto and returns it in cents. Amounts in other currencies are refused.
+func ParseZAR(amount string) (int64, error) {
+	m, err := ParseAmount(amount, AmountAuto)
+	if err != nil {
+		return 0, err
+	}
+	if m.Currency != "ZAR" {
+		return 0, &AmountError{Amount: amount, Err: fmt.Errorf("%w: currency is %s", ErrInvalidAmount, m.Currency)}
+	}
+	return m.Amount, nil
+}
+
+func trimSign(s string) (string, bool) {
+	if strings.HasPrefix(s, "-") {
+		return strings.TrimSpace(s[1:]), true
+	}
+	if strings.HasSuffix(s, "-") {
+		return strings.TrimSpace(s[:len(s)-1]), true
+	}
+	return strings.TrimPrefix(s, "+"), false
+}
+
+// trimCurrency removes a currency symbol or code from the start or end of s.
+func trimCurrency(s string) (string, string) {
+	start := strings.IndexFunc(s, func(r rune) bool { return unicode.IsDigit(r) || strings.ContainsRune("-+.,", r) })
+	if start < 0 {
+		return s, ""
+	}
+	end := strings.LastIndexFunc(s, func(r rune) bool { return unicode.IsDigit(r) || r == '-' })
+	prefix, suffix := strings.TrimSpace(s[:start]), strings.TrimSpace(s[end+1:])
+
+	symbol, rest := prefix, s[start:]
+	if prefix == "" {
+		symbol, rest = suffix, s[:end+1]
+	} else if suffix != "" {
+		return s, ""
+	}
+	if symbol == "" {
+		return s, ""
+	}
+	if code, ok := stdext.CurrencyForSymbol(symbol); ok {
+		return strings.TrimSpace(rest), code
+	}
+	if stdext.IsCurrencyCode(symbol) {
+		return strings.TrimSpace(rest), symbol
+	}
+	return s, ""
+}
+
+// splitAmount returns the digits before and after the decimal separator.
+func splitAmount(s string, style AmountStyle) (string, string, error) {
+	if s == "" {
+		return "", "", ErrInvalidAmount
+	}
+	group := style.Group
+	decimal := style.Decimal
+	if decimal == 0 {
+		var err error
+		decimal, group, err = inferSeparators(s, group)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	whole, fraction := s, ""
+	if i := strings.LastIndex(s, string(decimal)); i >= 0 {
+		whole, fraction = s[:i], s[i+len(string(decimal)):]
+	}
+	if !onlyDigits(fraction) {
+		return "", "", ErrInvalidAmount
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	whole, err := ungroup(whole, group)
+	return whole, fraction, err
+}
+
+// inferSeparators picks the decimal separator for AmountAuto, and adds the other of '.' and ',' to the grouping separators.
+func inferSeparators(s, group string) (rune, string, error) {
+	dots, commas := strings.Count(s, "."), strings.Count(s, ",")
+	switch {
+	case dots > 0 && commas > 0:
+		if strings.LastIndex(s, ".") > strings.LastIndex(s, ",") {
+			return '.', group + ",", nil
+		}
+		return ',', group + ".", nil
+	case dots > 1:
+		return ',', group + ".", nil
+	case commas > 1:
+		return '.', group + ",", nil
+	case dots == 0 && commas == 0:
+		return '.', group, nil
+	}
+
+	sep := "."
+	if commas == 1 {
+		sep = ","
+	}
+	i := strings.Index(s, sep)
+	if len(s)-i-1 == 3 && strings.TrimLeft(s[:i], "0") != "" {
+		return 0, "", ErrAmbiguousAmount
+	}
+	return rune(sep[0]), group, nil
+}
+
+// ungroup removes grouping separators from whole, checking that they separate groups of three digits.
+func ungroup(whole, group string) (string, error) {
+	parts := strings.FieldsFunc(whole, func(r rune) bool { return strings.ContainsRune(group, r) })
+	if len(parts) == 0 {
+		return "", ErrInvalidAmount
+	}
+
+	var sep rune
+	for _, r := range whole {
+		if strings.ContainsRune(group, r) {
+			if sep != 0 && r != sep {
+				return "", ErrInvalidGrouping
+			}
+			sep = r
+		}
+	}
+	if sep != 0 && strings.Contains(whole, string(sep)+string(sep)) {
+		return "", ErrInvalidGrouping
+	}
+
+	for i, p := range parts {
+		if !onlyDigits(p) || p == "" {
+			return "", ErrInvalidAmount
+		}
+		if len(parts) > 1 && (i > 0 && len(p) != 3 || i == 0 && len(p) > 3) {
+			return "", ErrInvalidGrouping
+		}
+	}
+	return strings.Join(parts, ""), nil
+}
+
+func onlyDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
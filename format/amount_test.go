@@ -0,0 +1,88 @@
+package format
+
+import (
+	"github.com/Direct-Debit/go-commons/stdext"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tables := []struct {
+		in    string
+		style AmountStyle
+		out   stdext.Money
+	}{
+		{"R 12.5", AmountAuto, stdext.ZAR(1250)},
+		{"R12,50", AmountAuto, stdext.ZAR(1250)},
+		{"1,234.56", AmountAuto, stdext.ZAR(123456)},
+		{"1 234,56", AmountAuto, stdext.ZAR(123456)},
+		{"1.234.567,89", AmountAuto, stdext.ZAR(123456789)},
+		{"1,234,567", AmountAuto, stdext.ZAR(123456700)},
+		{"1234", AmountAuto, stdext.ZAR(123400)},
+		{"0,07", AmountAuto, stdext.ZAR(7)},
+		{".5", AmountAuto, stdext.ZAR(50)},
+		{"-R 12.50", AmountAuto, stdext.ZAR(-1250)},
+		{"R -12.50", AmountAuto, stdext.ZAR(-1250)},
+		{"12.50-", AmountAuto, stdext.ZAR(-1250)},
+		{"(R 1 234,56)", AmountAuto, stdext.ZAR(-123456)},
+		{"+5", AmountAuto, stdext.ZAR(500)},
+		{"1,234.56 USD", AmountAuto, stdext.NewMoney(123456, "USD")},
+		{"$1,234.56", AmountAuto, stdext.NewMoney(123456, "USD")},
+		{"P 12,50", AmountAuto, stdext.NewMoney(1250, "BWP")},
+		{"¥1,234", AmountInternational, stdext.NewMoney(1234, "JPY")},
+		{"N$ 10", AmountAuto, stdext.NewMoney(1000, "NAD")},
+		{"JPY 1,234,567", AmountAuto, stdext.NewMoney(1234567, "JPY")},
+		{"12.345 KWD", AmountInternational, stdext.NewMoney(12345, "KWD")},
+		{"1 234,56", AmountZA, stdext.ZAR(123456)},
+		{"1 234,56", AmountZA, stdext.ZAR(123456)},
+		{"1,234", AmountInternational, stdext.ZAR(123400)},
+		{"1,234 KWD", AmountZA, stdext.NewMoney(1234, "KWD")},
+		{"1'234.5", AmountInternational, stdext.ZAR(123450)},
+		{"10", AmountStyle{Decimal: '.', Currency: "EUR"}, stdext.NewMoney(1000, "EUR")},
+	}
+
+	for _, table := range tables {
+		result, err := ParseAmount(table.in, table.style)
+		assert.NoError(t, err, table.in)
+		assert.Equal(t, table.out, result, table.in)
+	}
+}
+
+func TestParseAmountErrors(t *testing.T) {
+	tables := []struct {
+		in    string
+		style AmountStyle
+		err   error
+	}{
+		{"1,234", AmountAuto, ErrAmbiguousAmount},
+		{"R 1.0055", AmountAuto, ErrTooManyDecimals},
+		{"1,234", AmountZA, ErrTooManyDecimals},
+		{"1,234.567", AmountInternational, ErrTooManyDecimals},
+		{"1.234,56", AmountZA, ErrInvalidAmount},
+		{"1,23,456.00", AmountAuto, ErrInvalidGrouping},
+		{"1 234,567.00", AmountInternational, ErrInvalidGrouping},
+		{"12 34", AmountAuto, ErrInvalidGrouping},
+		{"(-5)", AmountAuto, ErrInvalidAmount},
+		{"R", AmountAuto, ErrInvalidAmount},
+		{"", AmountAuto, ErrInvalidAmount},
+		{"12abc", AmountAuto, ErrInvalidAmount},
+		{"Rand 12", AmountAuto, ErrInvalidAmount},
+		{"99999999999999999999", AmountAuto, ErrAmountOutOfRange},
+	}
+
+	for _, table := range tables {
+		_, err := ParseAmount(table.in, table.style)
+		assert.ErrorIs(t, err, table.err, table.in)
+		var amountErr *AmountError
+		assert.ErrorAs(t, err, &amountErr, table.in)
+	}
+}
+
+func TestParseZAR(t *testing.T) {
+	cents, err := ParseZAR("2,194.50")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(219450), cents)
+
+	_, err = ParseZAR("$5")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
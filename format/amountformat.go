@@ -0,0 +1,82 @@
+package format
+
+import (
+	"github.com/Direct-Debit/go-commons/stdext"
+)
+
+// CurrencyDisplay selects how AmountFormat shows the currency.
+type CurrencyDisplay int
+
+const (
+	CurrencyNone   CurrencyDisplay = iota
+	CurrencySymbol                 // e.g. "R 12,50"
+	CurrencyCode                   // e.g. "ZAR 12.50"
+)
+
+// NegativeStyle selects how AmountFormat shows negative amounts.
+type NegativeStyle int
+
+const (
+	NegativeMinus         NegativeStyle = iota // -R 12,50
+	NegativeParentheses                        // (R 12,50), the accounting style
+	NegativeTrailingMinus                      // R 12,50-
+)
+
+// AmountFormat formats amounts in cents, or other minor units, for people or files.
+type AmountFormat struct {
+	Decimal  string // Decimal separator
+	Group    string // Separator between groups of three digits, empty for no grouping
+	Currency CurrencyDisplay
+	// SymbolAfter puts the currency after the amount.
+	SymbolAfter bool
+	// SymbolSpace puts a space between the currency and the amount. Codes always have a space.
+	SymbolSpace bool
+	Negative    NegativeStyle
+}
+
+var (
+	// AmountStatementZA is for en-ZA statements and letters: "R 1 234 567,89" and "-R 12,50".
+	AmountStatementZA = AmountFormat{Decimal: ",", Group: " ", Currency: CurrencySymbol, SymbolSpace: true}
+	// AmountAccountingZA is AmountStatementZA with negatives in parentheses: "(R 12,50)".
+	AmountAccountingZA = AmountFormat{Decimal: ",", Group: " ", Currency: CurrencySymbol, SymbolSpace: true,
+		Negative: NegativeParentheses}
+	// AmountInternationalCode shows the currency code with a decimal point and comma grouping: "ZAR 1,234,567.89".
+	AmountInternationalCode = AmountFormat{Decimal: ".", Group: ",", Currency: CurrencyCode}
+	// AmountBankFile is the convention of bank files that use a decimal comma and no grouping: "1234567,89".
+	AmountBankFile = AmountFormat{Decimal: ","}
+	// AmountMachine is a plain decimal for CSV exports and APIs: "1234567.89".
+	AmountMachine = AmountFormat{Decimal: "."}
+)
+
+// Cents formats an amount in ZAR cents.
+func (f AmountFormat) Cents(cents int64) string {
+	return f.Money(stdext.ZAR(cents))
+}
+
+// Money formats the amount with the number of decimals and the symbol or code of its currency.
+func (f AmountFormat) Money(m stdext.Money) string {
+	s := f.withCurrency(m.Decimal(f.Decimal, f.Group), m.Currency)
+
+	if m.Amount >= 0 {
+		return s
+	}
+	switch f.Negative {
+	case NegativeParentheses:
+		return "(" + s + ")"
+	case NegativeTrailingMinus:
+		return s + "-"
+	default:
+		return "-" + s
+	}
+}
+
+func (f AmountFormat) withCurrency(amount, currency string) string {
+	switch f.Currency {
+	case CurrencySymbol:
+		return stdext.JoinSymbol(amount, stdext.CurrencySymbol(currency), f.SymbolAfter, f.SymbolSpace)
+	case CurrencyCode:
+		return stdext.JoinSymbol(amount, currency, f.SymbolAfter, true)
+	default:
+		return amount
+	}
+}
@@ -0,0 +1,49 @@
+package format
+
+import (
+	"github.com/Direct-Debit/go-commons/stdext"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestAmountFormat(t *testing.T) {
+	tables := []struct {
+		format AmountFormat
+		cents  int64
+		out    string
+	}{
+		{AmountStatementZA, 123456789, "R 1 234 567,89"},
+		{AmountStatementZA, -1250, "-R 12,50"},
+		{AmountStatementZA, 5, "R 0,05"},
+		{AmountAccountingZA, -123456, "(R 1 234,56)"},
+		{AmountAccountingZA, 123456, "R 1 234,56"},
+		{AmountInternationalCode, -123456789, "-ZAR 1,234,567.89"},
+		{AmountBankFile, 123456789, "1234567,89"},
+		{AmountMachine, -123, "-1.23"},
+		{AmountMachine, math.MinInt64, "-92233720368547758.08"},
+		{AmountFormat{Decimal: ".", Negative: NegativeTrailingMinus}, -1250, "12.50-"},
+		{AmountFormat{Decimal: ",", Group: ".", Currency: CurrencySymbol, SymbolAfter: true, SymbolSpace: true}, 123456, "1.234,56 R"},
+	}
+	for _, table := range tables {
+		assert.Equal(t, table.out, table.format.Cents(table.cents))
+	}
+
+	assert.Equal(t, "$1,234.56", AmountFormat{Decimal: ".", Group: ",", Currency: CurrencySymbol}.Money(stdext.NewMoney(123456, "USD")))
+	assert.Equal(t, "JPY 1,234", AmountInternationalCode.Money(stdext.NewMoney(1234, "JPY")))
+	assert.Equal(t, "KWD 1.234", AmountInternationalCode.Money(stdext.NewMoney(1234, "KWD")))
+	usd := AmountFormat{Decimal: ".", Group: ",", Currency: CurrencySymbol}
+	assert.Equal(t, "KWD 1.234", usd.Money(stdext.NewMoney(1234, "KWD")), "a code used as symbol gets a space")
+	assert.Equal(t, stdext.NewMoney(123456, "USD").Format("en-US"), usd.Money(stdext.NewMoney(123456, "USD")))
+}
+
+func TestAmountFormatParses(t *testing.T) {
+	for _, f := range []AmountFormat{AmountStatementZA, AmountAccountingZA, AmountInternationalCode, AmountMachine} {
+		for _, cents := range []int64{0, 7, -1250, 123456789, -123456789} {
+			s := f.Cents(cents)
+			m, err := ParseAmount(s, AmountAuto)
+			assert.NoError(t, err, s)
+			assert.Equal(t, stdext.ZAR(cents), m, s)
+		}
+	}
+}
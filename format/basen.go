@@ -0,0 +1,270 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrEncodingOverflow = errors.New("value does not fit")
+	ErrInvalidDigit     = errors.New("invalid digit")
+	ErrInvalidCheck     = errors.New("check character is wrong")
+)
+
+// Encoding converts numbers to and from text in a base with a custom alphabet, e.g. for compact sequence numbers.
+type Encoding struct {
+	alphabet []rune
+	values   map[rune]int
+	ignore   string
+}
+
+var (
+	// Base36 uses digits and upper case letters, and decodes lower case letters too.
+	Base36 = newEncoding("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ", true, nil, "")
+	// Base62 uses digits, upper case and lower case letters.
+	Base62 = newEncoding("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", false, nil, "")
+	// Crockford32 is Douglas Crockford's base 32, which leaves out I, L, O and U to avoid confusion.
+	// When decoding, it accepts lower case letters, reads I and L as 1 and O as 0, and ignores hyphens.
+	Crockford32 = newEncoding("0123456789ABCDEFGHJKMNPQRSTVWXYZ", true, map[rune]rune{'I': '1', 'L': '1', 'O': '0'}, "-")
+)
+
+// NewEncoding creates an Encoding for the alphabet, where the first character is the digit zero.
+// Decoding is case-sensitive.
+func NewEncoding(alphabet string) (*Encoding, error) {
+	runes := []rune(alphabet)
+	if len(runes) < 2 {
+		return nil, fmt.Errorf("alphabet %q needs at least 2 characters", alphabet)
+	}
+	seen := make(map[rune]bool, len(runes))
+	for _, r := range runes {
+		if seen[r] {
+			return nil, fmt.Errorf("alphabet %q repeats %q", alphabet, r)
+		}
+		seen[r] = true
+	}
+	return newEncoding(alphabet, false, nil, ""), nil
+}
+
+func newEncoding(alphabet string, foldCase bool, aliases map[rune]rune, ignore string) *Encoding {
+	e := &Encoding{alphabet: []rune(alphabet), values: make(map[rune]int), ignore: ignore}
+	for i, r := range e.alphabet {
+		e.values[r] = i
+		if foldCase {
+			e.values[unicode.ToLower(r)] = i
+		}
+	}
+	for alias, r := range aliases {
+		e.values[alias] = e.values[r]
+		if foldCase {
+			e.values[unicode.ToLower(alias)] = e.values[r]
+		}
+	}
+	return e
+}
+
+// Base returns the number of characters in the alphabet.
+func (e *Encoding) Base() int {
+	return len(e.alphabet)
+}
+
+// Encode returns v in the encoding, without padding.
+func (e *Encoding) Encode(v uint64) string {
+	if v == 0 {
+		return string(e.alphabet[0])
+	}
+	base := uint64(len(e.alphabet))
+	var digits []rune
+	for ; v > 0; v /= base {
+		digits = append(digits, e.alphabet[v%base])
+	}
+	reverse(digits)
+	return string(digits)
+}
+
+// EncodeInt64 is Encode for signed values. Negative values are prefixed with a minus sign,
+// so they can't be decoded with an alphabet that has '-' as a digit.
+func (e *Encoding) EncodeInt64(v int64) string {
+	if v < 0 {
+		return "-" + e.Encode(uint64(-(v+1))+1)
+	}
+	return e.Encode(uint64(v))
+}
+
+// EncodeBig is Encode for arbitrarily large values. Negative values are refused.
+func (e *Encoding) EncodeBig(v *big.Int) (string, error) {
+	if v.Sign() < 0 {
+		return "", fmt.Errorf("cannot encode negative %v", v)
+	}
+	if v.Sign() == 0 {
+		return string(e.alphabet[0]), nil
+	}
+	base := big.NewInt(int64(len(e.alphabet)))
+	rest := new(big.Int).Set(v)
+	digit := new(big.Int)
+	var digits []rune
+	for rest.Sign() > 0 {
+		rest.QuoRem(rest, base, digit)
+		digits = append(digits, e.alphabet[digit.Int64()])
+	}
+	reverse(digits)
+	return string(digits), nil
+}
+
+// EncodeWidth returns v in the encoding, padded with zero digits to exactly width characters.
+// Values that need more than width characters return ErrEncodingOverflow, rather than being truncated.
+func (e *Encoding) EncodeWidth(v uint64, width int) (string, error) {
+	return e.Pad(e.Encode(v), width)
+}
+
+// Pad pads an encoded value with zero digits to exactly width characters, or returns ErrEncodingOverflow if it is longer.
+// The zeros go after the minus sign of a negative value from EncodeInt64, so "-5" padded to 4 is "-005".
+func (e *Encoding) Pad(encoded string, width int) (string, error) {
+	missing := width - len([]rune(encoded))
+	if missing < 0 {
+		return "", fmt.Errorf("%w: %s is longer than %d characters", ErrEncodingOverflow, encoded, width)
+	}
+	sign := ""
+	if e.hasSign(encoded) {
+		sign, encoded = "-", encoded[1:]
+	}
+	return sign + strings.Repeat(string(e.alphabet[0]), missing) + encoded, nil
+}
+
+// hasSign reports whether s starts with a minus sign, which it can only do if '-' is not a digit.
+func (e *Encoding) hasSign(s string) bool {
+	_, digit := e.values['-']
+	return !digit && strings.HasPrefix(s, "-")
+}
+
+// MaxWidth returns the largest value that fits in width characters.
+func (e *Encoding) MaxWidth(width int) *big.Int {
+	max := new(big.Int).Exp(big.NewInt(int64(len(e.alphabet))), big.NewInt(int64(width)), nil)
+	return max.Sub(max, big.NewInt(1))
+}
+
+func reverse(r []rune) {
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+}
+
+// digits returns the values of the digits in s, skipping ignored characters.
+func (e *Encoding) digits(s string) ([]int, error) {
+	result := make([]int, 0, len(s))
+	for _, r := range s {
+		if strings.ContainsRune(e.ignore, r) {
+			continue
+		}
+		v, ok := e.values[r]
+		if !ok {
+			return nil, fmt.Errorf("%w %q in %q", ErrInvalidDigit, r, s)
+		}
+		result = append(result, v)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("%w: %q has no digits", ErrInvalidDigit, s)
+	}
+	return result, nil
+}
+
+// Decode reads an encoded value. Values too large for uint64 return ErrEncodingOverflow.
+func (e *Encoding) Decode(s string) (uint64, error) {
+	digits, err := e.digits(s)
+	if err != nil {
+		return 0, err
+	}
+	base := uint64(len(e.alphabet))
+	var result uint64
+	for _, d := range digits {
+		if result > (math.MaxUint64-uint64(d))/base {
+			return 0, fmt.Errorf("%w: %s is too large for uint64", ErrEncodingOverflow, s)
+		}
+		result = result*base + uint64(d)
+	}
+	return result, nil
+}
+
+// DecodeInt64 reads a value written by EncodeInt64.
+func (e *Encoding) DecodeInt64(s string) (int64, error) {
+	neg := e.hasSign(s)
+	if neg {
+		s = s[1:]
+	}
+	v, err := e.Decode(s)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case neg && v <= math.MaxInt64+1:
+		return int64(-v), nil
+	case !neg && v <= math.MaxInt64:
+		return int64(v), nil
+	default:
+		return 0, fmt.Errorf("%w: %s is too large for int64", ErrEncodingOverflow, s)
+	}
+}
+
+// DecodeBig reads an arbitrarily large encoded value.
+func (e *Encoding) DecodeBig(s string) (*big.Int, error) {
+	digits, err := e.digits(s)
+	if err != nil {
+		return nil, err
+	}
+	base := big.NewInt(int64(len(e.alphabet)))
+	result := new(big.Int)
+	for _, d := range digits {
+		result.Mul(result, base)
+		result.Add(result, big.NewInt(int64(d)))
+	}
+	return result, nil
+}
+
+// CheckChar returns the Luhn mod N check character of an encoded value, where N is the base.
+// It catches every single character error and most swaps of adjacent characters.
+func (e *Encoding) CheckChar(s string) (rune, error) {
+	digits, err := e.digits(s)
+	if err != nil {
+		return 0, err
+	}
+	n := len(e.alphabet)
+	sum := luhnModN(digits, 2, n)
+	return e.alphabet[(n-sum%n)%n], nil
+}
+
+// AppendCheck returns the encoded value with its check character at the end.
+func (e *Encoding) AppendCheck(s string) (string, error) {
+	c, err := e.CheckChar(s)
+	if err != nil {
+		return "", err
+	}
+	return s + string(c), nil
+}
+
+// VerifyCheck checks the check character at the end of s, and returns s without it.
+// Ignored characters after the check character, like a trailing hyphen in Crockford32, are removed too.
+func (e *Encoding) VerifyCheck(s string) (string, error) {
+	digits, err := e.digits(s)
+	if err != nil {
+		return "", err
+	}
+	if len(digits) < 2 || luhnModN(digits, 1, len(e.alphabet))%len(e.alphabet) != 0 {
+		return "", fmt.Errorf("%w in %q", ErrInvalidCheck, s)
+	}
+	runes := []rune(strings.TrimRight(s, e.ignore))
+	return string(runes[:len(runes)-1]), nil
+}
+
+// luhnModN sums the digits from the right, multiplying alternate digits by 2 starting with factor.
+func luhnModN(digits []int, factor, n int) int {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		addend := factor * digits[i]
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return sum
+}
@@ -0,0 +1,120 @@
+package format
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/big"
+	"testing"
+)
+
+func TestEncoding(t *testing.T) {
+	assert.Equal(t, "LFLS", Base36.Encode(1000000))
+	assert.Equal(t, "4C92", Base62.Encode(1000000))
+	assert.Equal(t, "YGJ0", Crockford32.Encode(1000000))
+	assert.Equal(t, "3W5E11264SGSF", Base36.Encode(math.MaxUint64))
+	assert.Equal(t, "-1Y2P0IJ32E8E8", Base36.EncodeInt64(math.MinInt64))
+
+	v, err := Base36.Decode("3w5e11264sgsf")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), v)
+	_, err = Base36.Decode("3W5E11264SGSG")
+	assert.ErrorIs(t, err, ErrEncodingOverflow)
+	i, err := Base36.DecodeInt64("-1Y2P0IJ32E8E8")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MinInt64), i)
+	_, err = Base36.DecodeInt64("1Y2P0IJ32E8E8")
+	assert.ErrorIs(t, err, ErrEncodingOverflow)
+
+	v, err = Crockford32.Decode("ygj-o")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000000), v)
+	v, err = Crockford32.Decode("IL")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(33), v)
+	_, err = Crockford32.Decode("U")
+	assert.ErrorIs(t, err, ErrInvalidDigit)
+	_, err = Base62.Decode("")
+	assert.ErrorIs(t, err, ErrInvalidDigit)
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	s, err := Base62.EncodeBig(huge)
+	assert.NoError(t, err)
+	decoded, err := Base62.DecodeBig(s)
+	assert.NoError(t, err)
+	assert.Equal(t, huge, decoded)
+	_, err = Base62.EncodeBig(big.NewInt(-1))
+	assert.Error(t, err)
+}
+
+func TestEncodingWidth(t *testing.T) {
+	s, err := Base36.EncodeWidth(1322, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, "0010Q", s)
+	_, err = Base36.EncodeWidth(1679616, 4)
+	assert.ErrorIs(t, err, ErrEncodingOverflow)
+	assert.Equal(t, big.NewInt(1679615), Base36.MaxWidth(4))
+
+	s, err = Base36.Pad(Base36.EncodeInt64(-5), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "-005", s, "the sign goes before the zeros")
+	i, err := Base36.DecodeInt64(s)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-5), i)
+}
+
+func TestNewEncoding(t *testing.T) {
+	decimal, err := NewEncoding("0123456789")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, decimal.Base())
+
+	// The standard Luhn example
+	s, err := decimal.AppendCheck("7992739871")
+	assert.NoError(t, err)
+	assert.Equal(t, "79927398713", s)
+
+	dashed, err := NewEncoding("-ABCDEFGHIJ")
+	assert.NoError(t, err)
+	i, err := dashed.DecodeInt64("-B")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), i, "a '-' in the alphabet is a digit, not a sign")
+	s, err = dashed.Pad("B", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, "--B", s)
+
+	_, err = NewEncoding("0")
+	assert.Error(t, err)
+	_, err = NewEncoding("0120")
+	assert.Error(t, err)
+}
+
+func TestCheckChar(t *testing.T) {
+	for _, e := range []*Encoding{Base36, Base62, Crockford32} {
+		payload := e.Encode(987654321)
+		s, err := e.AppendCheck(payload)
+		assert.NoError(t, err)
+		verified, err := e.VerifyCheck(s)
+		assert.NoError(t, err)
+		assert.Equal(t, payload, verified)
+
+		// Every single character error is detected
+		for i := range s {
+			for _, r := range e.alphabet {
+				if rune(s[i]) == r {
+					continue
+				}
+				changed := s[:i] + string(r) + s[i+1:]
+				_, err := e.VerifyCheck(changed)
+				assert.ErrorIs(t, err, ErrInvalidCheck, changed)
+			}
+		}
+	}
+
+	s, err := Crockford32.AppendCheck("ABC")
+	assert.NoError(t, err)
+	verified, err := Crockford32.VerifyCheck(s + "-")
+	assert.NoError(t, err)
+	assert.Equal(t, "ABC", verified, "a trailing separator is not taken for the check character")
+
+	_, err = Base36.VerifyCheck("A")
+	assert.ErrorIs(t, err, ErrInvalidCheck)
+}
@@ -0,0 +1,157 @@
+package format
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAmbiguousDate is wrapped by AmbiguousDateError.
+var ErrAmbiguousDate = errors.New("date is ambiguous")
+
+// AmbiguousDateError is returned when a value parses to different dates with different layouts of a DateFormat,
+// like "01/02/2023" with DDsMMsYYYY and MMsDDsYYYY.
+type AmbiguousDateError struct {
+	Value string
+	Dates []time.Time
+}
+
+func (e *AmbiguousDateError) Error() string {
+	dates := make([]string, len(e.Dates))
+	for i, d := range e.Dates {
+		dates[i] = d.Format(DateShortDashes)
+	}
+	return fmt.Sprintf("%v: %q could be %s", ErrAmbiguousDate, e.Value, strings.Join(dates, " or "))
+}
+
+func (e *AmbiguousDateError) Unwrap() error {
+	return ErrAmbiguousDate
+}
+
+// DateFormat is a prioritized list of layouts for a date, e.g. the layouts one bank uses in its files.
+type DateFormat struct {
+	// Layouts are tried in order when parsing. Dates are formatted with the first one.
+	Layouts []string
+	// RejectAmbiguous makes Parse try every layout, and fail if they give different dates,
+	// instead of returning the date from the first layout that matches.
+	RejectAmbiguous bool
+}
+
+// Parse reads a date with the first layout that matches the value, ignoring surrounding spaces.
+func (f DateFormat) Parse(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	var result time.Time
+	var dates []time.Time
+	var firstErr error
+	for _, layout := range f.Layouts {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if !f.RejectAmbiguous {
+			return t, nil
+		}
+		if len(dates) == 0 {
+			result = t
+		}
+		if !containsTime(dates, t) {
+			dates = append(dates, t)
+		}
+	}
+
+	switch {
+	case len(dates) > 1:
+		return time.Time{}, &AmbiguousDateError{Value: value, Dates: dates}
+	case len(dates) == 1:
+		return result, nil
+	case firstErr != nil:
+		return time.Time{}, fmt.Errorf("could not parse date %q with %s: %w", value, strings.Join(f.Layouts, ", "), firstErr)
+	default:
+		return time.Time{}, errors.New("date format has no layouts")
+	}
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, other := range times {
+		if other.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Format formats the date with the first layout.
+func (f DateFormat) Format(t time.Time) string {
+	if len(f.Layouts) == 0 {
+		return ""
+	}
+	return t.Format(f.Layouts[0])
+}
+
+// DateAuto accepts the common unambiguous date layouts, and refuses dates that could be day/month or month/day.
+var DateAuto = DateFormat{
+	Layouts: []string{
+		DateShortDashes, DateShort8, DateShortSlashes, DDsMMsYYYY, MMsDDsYYYY, DDdMMdYYYY, DDdMMMdYY, RFC3339NanoFixed,
+	},
+	RejectAmbiguous: true,
+}
+
+var (
+	dateFormatsLock sync.RWMutex
+	dateFormats     = map[string]DateFormat{
+		"DateShort6":          {Layouts: []string{DateShort6}},
+		"DateShort6Slashes":   {Layouts: []string{DateShort6Slashes}},
+		"DateShort8":          {Layouts: []string{DateShort8}},
+		"DateShortSlashes":    {Layouts: []string{DateShortSlashes}},
+		"DateShortDashes":     {Layouts: []string{DateShortDashes}},
+		"DateTimeCompact":     {Layouts: []string{DateTimeCompact}},
+		"DateTimeShort":       {Layouts: []string{DateTimeShort}},
+		"DateTimeShortDashes": {Layouts: []string{DateTimeShortDashes}},
+		"DDsMMsYYYY":          {Layouts: []string{DDsMMsYYYY}},
+		"MMsDDsYYYY":          {Layouts: []string{MMsDDsYYYY}},
+		"DDdMMdYYYY":          {Layouts: []string{DDdMMdYYYY}},
+		"DDdMMMdYY":           {Layouts: []string{DDdMMMdYY}},
+		"MonthYY":             {Layouts: []string{MonthYY}},
+		"MMYY":                {Layouts: []string{MMYY}},
+		"YYYYdMM":             {Layouts: []string{YYYYdMM}},
+		"RFC3339NanoFixed":    {Layouts: []string{RFC3339NanoFixed}},
+		"DateAuto":            DateAuto,
+	}
+)
+
+// RegisterDateFormat names a DateFormat, e.g. RegisterDateFormat("absa-debits", DateFormat{Layouts: []string{DateShort8}}).
+// The layout constants in this package are registered by their names, like "DateShort8", and DateAuto as "DateAuto".
+func RegisterDateFormat(name string, f DateFormat) error {
+	if len(f.Layouts) == 0 {
+		return fmt.Errorf("date format %s has no layouts", name)
+	}
+	// Copied, so the caller changing its slice later doesn't change the registered format
+	f.Layouts = append([]string(nil), f.Layouts...)
+
+	dateFormatsLock.Lock()
+	defer dateFormatsLock.Unlock()
+	dateFormats[name] = f
+	return nil
+}
+
+// LookupDateFormat returns the DateFormat registered with the name.
+func LookupDateFormat(name string) (DateFormat, bool) {
+	dateFormatsLock.RLock()
+	defer dateFormatsLock.RUnlock()
+	f, ok := dateFormats[name]
+	return f, ok
+}
+
+// ResolveDateFormat returns the DateFormat registered with the name, or a DateFormat with the value as its only layout.
+// Use it where a setting can be either the name of a format or a layout.
+func ResolveDateFormat(nameOrLayout string) DateFormat {
+	if f, ok := LookupDateFormat(nameOrLayout); ok {
+		return f
+	}
+	return DateFormat{Layouts: []string{nameOrLayout}}
+}
@@ -0,0 +1,62 @@
+package format
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDateFormat(t *testing.T) {
+	f := DateFormat{Layouts: []string{DateShort8, DDsMMsYYYY}}
+	d, err := f.Parse("20230201")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), d)
+	d, err = f.Parse(" 01/02/2023 ")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC), d)
+	assert.Equal(t, "20230201", f.Format(d))
+	_, err = f.Parse("2023-02-01")
+	assert.Error(t, err)
+
+	d, err = DateAuto.Parse("13/02/2023")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 2, 13, 0, 0, 0, 0, time.UTC), d)
+	d, err = DateAuto.Parse("02/13/2023")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 2, 13, 0, 0, 0, 0, time.UTC), d)
+	d, err = DateAuto.Parse("01/01/2023")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), d)
+
+	_, err = DateAuto.Parse("01/02/2023")
+	assert.ErrorIs(t, err, ErrAmbiguousDate)
+	var ambiguous *AmbiguousDateError
+	assert.ErrorAs(t, err, &ambiguous)
+	assert.Equal(t, []time.Time{
+		time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+	}, ambiguous.Dates)
+
+	_, err = DateFormat{}.Parse("2023-02-01")
+	assert.Error(t, err)
+}
+
+func TestDateFormatRegistry(t *testing.T) {
+	assert.NoError(t, RegisterDateFormat("test-bank", DateFormat{Layouts: []string{DDdMMdYYYY, DateShort8}}))
+	assert.Error(t, RegisterDateFormat("empty", DateFormat{}))
+
+	f, ok := LookupDateFormat("test-bank")
+	assert.True(t, ok)
+	assert.Equal(t, "01-02-2023", f.Format(time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)))
+
+	assert.Equal(t, DateFormat{Layouts: []string{DateShort8}}, ResolveDateFormat("DateShort8"))
+	assert.Equal(t, DateFormat{Layouts: []string{"02 Jan"}}, ResolveDateFormat("02 Jan"))
+	_, ok = LookupDateFormat("02 Jan")
+	assert.False(t, ok)
+
+	layouts := []string{DateShort8}
+	assert.NoError(t, RegisterDateFormat("test-copied", DateFormat{Layouts: layouts}))
+	layouts[0] = DDdMMdYYYY
+	f, _ = LookupDateFormat("test-copied")
+	assert.Equal(t, []string{DateShort8}, f.Layouts, "the layouts are copied when registering")
+}
@@ -2,8 +2,6 @@ package format
 
 import (
 	"fmt"
-	"github.com/Direct-Debit/go-commons/errlib"
-	"math"
 	"strconv"
 	"strings"
 )
@@ -18,6 +16,7 @@ const (
 	DateTimeShort       = "02/01/2006 15:04"
 	DateTimeShortDashes = "2006-01-02 15:04:05"
 	DDsMMsYYYY          = "02/01/2006"
+	MMsDDsYYYY          = "01/02/2006"
 	DDdMMdYYYY          = "02-01-2006"
 	DDdMMMdYY           = "02-Jan-06"
 	MonthYY             = "Jan06"
@@ -26,12 +25,14 @@ const (
 	RFC3339NanoFixed    = "2006-01-02T15:04:05.000000000Z07:00"
 )
 
+// CentToCommaRand formats cents as rands with a decimal comma and no grouping, like "1234,56" and "-1,23".
+// See AmountFormat for grouping, currency symbols and other conventions.
 func CentToCommaRand(cent int) string {
-	r := cent / 100
-	c := cent % 100
-	return fmt.Sprintf("%d,%02d", r, c)
+	return AmountBankFile.Cents(int64(cent))
 }
 
+// Deprecated: AnyAmountToCent ignores decimal separators, so "R 12.5" is 125 cents. Use ParseAmount instead.
+// It is kept as is for callers that pass whole numbers of cents.
 func AnyAmountToCent(amount string) (int, error) {
 	replacements := []string{",", "", ".", "", "R", "", " ", ""}
 	if len(replacements)%2 != 0 {
@@ -46,65 +47,24 @@ func AnyAmountToCent(amount string) (int, error) {
 	return res, nil
 }
 
-func intToBase36Digit(i int) (string, error) {
-	if i < 0 || i >= 36 {
-		return "", fmt.Errorf("can't convert %v to base 36 digit", i)
-	}
-	if i < 10 {
-		return strconv.Itoa(i), nil
-	}
-	alphaPos := i - 10
-	iVal := 'A' + rune(alphaPos)
-	return string(iVal), nil
-}
-
-// Convert an integer to base36 where A-Z represent digits with values 10-35
+// Convert an integer to base36 where A-Z represent digits with values 10-35.
+// Negative integers are prefixed with a minus sign. See Base36 for padding and check characters.
 func IntToBase36(i int) string {
-	if i == 0 {
-		return "0"
-	}
-
-	digits := make([]string, 1)
-	unconverted := i
-	for exp := 0; unconverted > 0; exp++ {
-		digitPos := int(math.Pow(36.0, float64(exp)))
-		digitVal := (unconverted / digitPos) % 36
-
-		digit, err := intToBase36Digit(digitVal)
-		errlib.FatalError(err, "Our math is broken")
-		digits = append(digits, digit)
-
-		unconverted = unconverted - (digitVal * digitPos)
-	}
-
-	var result strings.Builder
-	for idx := len(digits) - 1; idx >= 0; idx-- {
-		result.WriteString(digits[idx])
-	}
-	return result.String()
+	return Base36.EncodeInt64(int64(i))
 }
 
-func base36DigitToInt(d rune) (int, error) {
-	if d >= 'A' && d <= 'Z' {
-		return int(d-'A') + 10, nil
+// Convert a base36 to int where A-Z represent digits with values 10-35.
+// Lower case letters are accepted, and values too large for an int return an error.
+func Base36toInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
 	}
-	if d >= '0' && d <= '9' {
-		return int(d - '0'), nil
+	v, err := Base36.DecodeInt64(s)
+	if err != nil {
+		return 0, err
 	}
-	return 0, fmt.Errorf("can't read %c as base 36 digit", d)
-}
-
-// Convert a base36 to int where A-Z represent digits with values 10-35
-func Base36toInt(s string) (int, error) {
-	s = strings.ToUpper(s)
-	result := 0
-	for i, d := range s {
-		v, err := base36DigitToInt(d)
-		if err != nil {
-			return 0, err
-		}
-		digitPos := int(math.Pow(36.0, float64(len(s)-i-1)))
-		result += v * digitPos
+	if int64(int(v)) != v {
+		return 0, fmt.Errorf("%w: %s is too large for int", ErrEncodingOverflow, s)
 	}
-	return result, nil
+	return int(v), nil
 }
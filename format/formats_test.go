@@ -91,6 +91,8 @@ func TestCentToCommaRand(t *testing.T) {
 		{1296, "12,96"},
 		{1322, "13,22"},
 		{1000000, "10000,00"},
+		{-123, "-1,23"},
+		{-5, "-0,05"},
 	}
 
 	for _, table := range tables {
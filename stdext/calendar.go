@@ -0,0 +1,273 @@
+package stdext
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Holiday is a public holiday on a date, at midnight UTC.
+type Holiday struct {
+	Date time.Time
+	Name string
+}
+
+func (h Holiday) String() string {
+	return fmt.Sprintf("%s %s", h.Date.Format("2006-01-02"), h.Name)
+}
+
+// HolidaySource provides the holidays in a year.
+type HolidaySource interface {
+	Holidays(year int) []Holiday
+}
+
+// HolidayList is a HolidaySource for a fixed set of dates, like ad-hoc declared holidays.
+type HolidayList []Holiday
+
+func (l HolidayList) Holidays(year int) []Holiday {
+	var result []Holiday
+	for _, h := range l {
+		if h.Date.Year() == year {
+			result = append(result, h)
+		}
+	}
+	return result
+}
+
+// ParseHolidayList reads a holiday on every line, as a date in the form 2006-01-02 followed by an optional name.
+// Blank lines and lines starting with # are ignored.
+// Use it with holidays stored in config, or loaded with LoadHolidayList.
+func ParseHolidayList(content string) (HolidayList, error) {
+	var result HolidayList
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		date, name, _ := strings.Cut(line, " ")
+		d, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, WrapError(err, "invalid holiday on line %d", i+1)
+		}
+		result = append(result, Holiday{Date: d, Name: strings.TrimSpace(name)})
+	}
+	return result, nil
+}
+
+// HolidayLoader is the part of fileio.FileStore that LoadHolidayList uses.
+type HolidayLoader interface {
+	Load(path string) (content string, err error)
+}
+
+// LoadHolidayList loads a file in the format read by ParseHolidayList, e.g. from a fileio.FileStore.
+func LoadHolidayList(store HolidayLoader, path string) (HolidayList, error) {
+	content, err := store.Load(path)
+	if err != nil {
+		return nil, WrapError(err, "could not load holidays from %s", path)
+	}
+	return ParseHolidayList(content)
+}
+
+// ZAHolidays is the HolidaySource for the public holidays in the South African Public Holidays Act,
+// including the rule that a holiday on a Sunday is also observed on the Monday after it.
+// It doesn't include ad-hoc declared holidays, see ZAAdHocHolidays.
+type ZAHolidays struct{}
+
+func (ZAHolidays) Holidays(year int) []Holiday {
+	day := func(month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	easter := EasterSunday(year)
+	holidays := []Holiday{
+		{day(time.January, 1), "New Year's Day"},
+		{day(time.March, 21), "Human Rights Day"},
+		{easter.AddDate(0, 0, -2), "Good Friday"},
+		{easter.AddDate(0, 0, 1), "Family Day"},
+		{day(time.April, 27), "Freedom Day"},
+		{day(time.May, 1), "Workers' Day"},
+		{day(time.June, 16), "Youth Day"},
+		{day(time.August, 9), "National Women's Day"},
+		{day(time.September, 24), "Heritage Day"},
+		{day(time.December, 16), "Day of Reconciliation"},
+		{day(time.December, 25), "Christmas Day"},
+		{day(time.December, 26), "Day of Goodwill"},
+	}
+
+	isHoliday := make(map[time.Time]bool, len(holidays))
+	for _, h := range holidays {
+		isHoliday[h.Date] = true
+	}
+	for _, h := range holidays {
+		monday := h.Date.AddDate(0, 0, 1)
+		if h.Date.Weekday() == time.Sunday && !isHoliday[monday] {
+			holidays = append(holidays, Holiday{monday, h.Name + " (observed)"})
+		}
+	}
+	return holidays
+}
+
+// ZAAdHocHolidays are the once-off public holidays declared in South Africa, like election days.
+// Add holidays declared after this list was last updated with a HolidayList, e.g. from LoadHolidayList.
+var ZAAdHocHolidays = HolidayList{
+	{time.Date(2009, 4, 22, 0, 0, 0, 0, time.UTC), "National elections"},
+	{time.Date(2011, 5, 18, 0, 0, 0, 0, time.UTC), "Local government elections"},
+	{time.Date(2014, 5, 7, 0, 0, 0, 0, time.UTC), "National elections"},
+	{time.Date(2016, 8, 3, 0, 0, 0, 0, time.UTC), "Local government elections"},
+	{time.Date(2019, 5, 8, 0, 0, 0, 0, time.UTC), "National elections"},
+	{time.Date(2021, 11, 1, 0, 0, 0, 0, time.UTC), "Local government elections"},
+	{time.Date(2022, 12, 27, 0, 0, 0, 0, time.UTC), "Declared public holiday"},
+	{time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC), "Rugby World Cup victory"},
+	{time.Date(2024, 5, 29, 0, 0, 0, 0, time.UTC), "National elections"},
+}
+
+// EasterSunday returns the date of Easter Sunday in the Gregorian calendar.
+func EasterSunday(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// Calendar answers which days are business days: weekdays that are not holidays in any of its sources.
+// Dates are compared by their calendar date in their own location, and results are at midnight UTC, like Date.
+// It is safe for concurrent use.
+type Calendar struct {
+	sources []HolidaySource
+
+	lock  sync.Mutex
+	years map[int]map[time.Time]string
+}
+
+// NewCalendar creates a Calendar with holidays from the given sources.
+func NewCalendar(sources ...HolidaySource) *Calendar {
+	return &Calendar{sources: sources}
+}
+
+// NewZACalendar creates a Calendar with the South African public holidays and ad-hoc holidays,
+// and any extra holiday sources.
+func NewZACalendar(extra ...HolidaySource) *Calendar {
+	return NewCalendar(append([]HolidaySource{ZAHolidays{}, ZAAdHocHolidays}, extra...)...)
+}
+
+func (c *Calendar) holidays(year int) map[time.Time]string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if h, ok := c.years[year]; ok {
+		return h
+	}
+
+	h := make(map[time.Time]string)
+	for _, s := range c.sources {
+		for _, holiday := range s.Holidays(year) {
+			d := Date(holiday.Date)
+			if _, ok := h[d]; !ok {
+				h[d] = holiday.Name
+			}
+		}
+	}
+	if c.years == nil {
+		c.years = make(map[int]map[time.Time]string)
+	}
+	c.years[year] = h
+	return h
+}
+
+// Holiday returns the name of the holiday on the date, if it is one.
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	d := Date(t)
+	name, ok := c.holidays(d.Year())[d]
+	return name, ok
+}
+
+// HolidaysIn returns the holidays in the year, sorted by date.
+func (c *Calendar) HolidaysIn(year int) []Holiday {
+	var result []Holiday
+	for d, name := range c.holidays(year) {
+		result = append(result, Holiday{Date: d, Name: name})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date.Before(result[j].Date) })
+	return result
+}
+
+// IsBusinessDay returns true if the date is a weekday and not a holiday.
+func (c *Calendar) IsBusinessDay(t time.Time) bool {
+	d := Date(t)
+	if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+		return false
+	}
+	_, holiday := c.Holiday(d)
+	return !holiday
+}
+
+// NextBusinessDay returns the first business day after the date.
+func (c *Calendar) NextBusinessDay(t time.Time) time.Time {
+	d := Date(t).AddDate(0, 0, 1)
+	for !c.IsBusinessDay(d) {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// PreviousBusinessDay returns the last business day before the date.
+func (c *Calendar) PreviousBusinessDay(t time.Time) time.Time {
+	d := Date(t).AddDate(0, 0, -1)
+	for !c.IsBusinessDay(d) {
+		d = d.AddDate(0, 0, -1)
+	}
+	return d
+}
+
+// AdjustToBusinessDay returns the date if it is a business day, otherwise the next business day.
+// Use it to move an action date that falls on a weekend or holiday.
+func (c *Calendar) AdjustToBusinessDay(t time.Time) time.Time {
+	if c.IsBusinessDay(t) {
+		return Date(t)
+	}
+	return c.NextBusinessDay(t)
+}
+
+// AddBusinessDays returns the date n business days after the date, or before it if n is negative.
+// If n is zero, the date is adjusted to a business day with AdjustToBusinessDay.
+func (c *Calendar) AddBusinessDays(t time.Time, n int) time.Time {
+	d := Date(t)
+	switch {
+	case n == 0:
+		return c.AdjustToBusinessDay(d)
+	case n > 0:
+		for ; n > 0; n-- {
+			d = c.NextBusinessDay(d)
+		}
+	default:
+		for ; n < 0; n++ {
+			d = c.PreviousBusinessDay(d)
+		}
+	}
+	return d
+}
+
+// BusinessDaysBetween counts the business days after start, up to and including end.
+// It is negative if end is before start.
+func (c *Calendar) BusinessDaysBetween(start, end time.Time) int {
+	s, e := Date(start), Date(end)
+	sign := 1
+	if e.Before(s) {
+		s, e, sign = e, s, -1
+	}
+	count := 0
+	for d := s.AddDate(0, 0, 1); !d.After(e); d = d.AddDate(0, 0, 1) {
+		if c.IsBusinessDay(d) {
+			count++
+		}
+	}
+	return sign * count
+}
@@ -0,0 +1,84 @@
+package stdext
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+type testLoader map[string]string
+
+func (l testLoader) Load(path string) (string, error) {
+	content, ok := l[path]
+	if !ok {
+		return "", assert.AnError
+	}
+	return content, nil
+}
+
+func TestEasterSunday(t *testing.T) {
+	assert.Equal(t, date(2021, time.April, 4), EasterSunday(2021))
+	assert.Equal(t, date(2024, time.March, 31), EasterSunday(2024))
+	assert.Equal(t, date(2025, time.April, 20), EasterSunday(2025))
+}
+
+func TestZACalendar(t *testing.T) {
+	c := NewZACalendar()
+
+	name, ok := c.Holiday(date(2024, time.March, 29))
+	assert.True(t, ok)
+	assert.Equal(t, "Good Friday", name)
+	name, ok = c.Holiday(date(2024, time.June, 17))
+	assert.True(t, ok)
+	assert.Equal(t, "Youth Day (observed)", name)
+	name, ok = c.Holiday(date(2024, time.May, 29))
+	assert.True(t, ok)
+	assert.Equal(t, "National elections", name)
+
+	// Christmas 2022 was on a Sunday, but the Monday was already the Day of Goodwill
+	_, ok = c.Holiday(date(2022, time.December, 26))
+	assert.True(t, ok)
+	assert.Len(t, ZAHolidays{}.Holidays(2022), 13)
+
+	assert.True(t, c.IsBusinessDay(date(2024, time.April, 2)))
+	assert.False(t, c.IsBusinessDay(date(2024, time.April, 1)))
+	assert.False(t, c.IsBusinessDay(date(2024, time.April, 6)))
+	assert.True(t, c.IsBusinessDay(time.Date(2024, time.April, 2, 23, 0, 0, 0, time.FixedZone("SAST", 2*60*60))))
+
+	assert.Equal(t, date(2024, time.April, 2), c.NextBusinessDay(date(2024, time.March, 28)))
+	assert.Equal(t, date(2024, time.March, 28), c.PreviousBusinessDay(date(2024, time.April, 2)))
+	assert.Equal(t, date(2024, time.April, 2), c.AdjustToBusinessDay(date(2024, time.March, 30)))
+	assert.Equal(t, date(2024, time.April, 2), c.AddBusinessDays(date(2024, time.March, 29), 0))
+	assert.Equal(t, date(2022, time.December, 29), c.AddBusinessDays(date(2022, time.December, 23), 2))
+	assert.Equal(t, date(2022, time.December, 23), c.AddBusinessDays(date(2022, time.December, 29), -2))
+	assert.Equal(t, 2, c.BusinessDaysBetween(date(2022, time.December, 23), date(2022, time.December, 29)))
+	assert.Equal(t, -2, c.BusinessDaysBetween(date(2022, time.December, 29), date(2022, time.December, 23)))
+
+	holidays := c.HolidaysIn(2023)
+	assert.Equal(t, Holiday{date(2023, time.January, 1), "New Year's Day"}, holidays[0])
+	assert.Equal(t, Holiday{date(2023, time.January, 2), "New Year's Day (observed)"}, holidays[1])
+}
+
+func TestHolidayList(t *testing.T) {
+	loader := testLoader{"holidays.txt": "# Declared holidays\n2030-03-04 Test day\n\n2031-01-02\n"}
+	list, err := LoadHolidayList(loader, "holidays.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, HolidayList{
+		{date(2030, time.March, 4), "Test day"},
+		{date(2031, time.January, 2), ""},
+	}, list)
+	assert.Equal(t, "2030-03-04 Test day", list[0].String())
+
+	c := NewZACalendar(list)
+	assert.False(t, c.IsBusinessDay(date(2030, time.March, 4)))
+	assert.True(t, c.IsBusinessDay(date(2030, time.March, 5)))
+
+	_, err = LoadHolidayList(loader, "missing.txt")
+	assert.Error(t, err)
+	_, err = ParseHolidayList("2030-13-01\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,418 @@
+package stdext
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of amounts that don't specify one, like plain cents scanned from a database.
+const DefaultCurrency = "ZAR"
+
+var (
+	ErrCurrencyMismatch = errors.New("currencies don't match")
+	ErrMoneyOverflow    = errors.New("amount overflows int64")
+)
+
+// RoundingMode selects how amounts that fall between two minor units are rounded.
+type RoundingMode int
+
+const (
+	RoundHalfUp   RoundingMode = iota // Halves are rounded away from zero
+	RoundHalfEven                     // Halves are rounded to the nearest even unit, also called banker's rounding
+)
+
+// Money is an amount in the minor units of its currency, e.g. cents for ZAR.
+// Arithmetic on Money is exact, and fails rather than mixing currencies or overflowing.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"` // ISO 4217 code
+}
+
+type currencyInfo struct {
+	symbol string
+	digits int
+}
+
+var currencies = map[string]currencyInfo{
+	"ZAR": {"R", 2},
+	"BWP": {"P", 2},
+	"NAD": {"N$", 2},
+	"LSL": {"L", 2},
+	"SZL": {"E", 2},
+	"USD": {"$", 2},
+	"EUR": {"€", 2},
+	"GBP": {"£", 2},
+	"JPY": {"¥", 0},
+	"KWD": {"KWD", 3},
+}
+
+// CurrencyDigits returns the number of decimal digits in the currency's minor unit, defaulting to 2 for unknown currencies.
+func CurrencyDigits(currency string) int {
+	if c, ok := currencies[currency]; ok {
+		return c.digits
+	}
+	return 2
+}
+
+// CurrencySymbol returns the currency's symbol, or its code if it has no known symbol.
+func CurrencySymbol(currency string) string {
+	if c, ok := currencies[currency]; ok {
+		return c.symbol
+	}
+	return currency
+}
+
+// CurrencyForSymbol returns the code of the currency with the given symbol, e.g. "ZAR" for "R".
+func CurrencyForSymbol(symbol string) (string, bool) {
+	for code, c := range currencies {
+		if c.symbol == symbol {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// IsCurrencyCode returns true if code looks like an ISO 4217 code: three upper case letters.
+func IsCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// NewMoney creates Money of amount minor units of the currency.
+func NewMoney(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ZAR creates Money from an amount in cents.
+func ZAR(cents int64) Money {
+	return Money{Amount: cents, Currency: "ZAR"}
+}
+
+// MoneyFromFloat converts an amount in major units, e.g. rands, to Money.
+// The float is converted using its shortest decimal representation, so 1.005 is treated as exactly 1.005 and not 1.00499999.
+func MoneyFromFloat(amount float64, currency string, mode RoundingMode) (Money, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return Money{}, fmt.Errorf("%v is not an amount", amount)
+	}
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(amount, 'f', -1, 64))
+	r.Mul(r, new(big.Rat).SetInt(pow10(CurrencyDigits(currency))))
+	minor, err := roundRat(r, mode)
+	return Money{Amount: minor, Currency: currency}, err
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// roundRat rounds r to a whole number with the rounding mode.
+func roundRat(r *big.Rat, mode RoundingMode) (int64, error) {
+	num, den := r.Num(), r.Denom()
+	q, m := new(big.Int).QuoRem(num, den, new(big.Int))
+
+	twice := new(big.Int).Abs(m)
+	twice.Lsh(twice, 1)
+	c := twice.Cmp(den)
+	if c > 0 || c == 0 && (mode == RoundHalfUp || q.Bit(0) == 1) {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+
+	if !q.IsInt64() {
+		return 0, ErrMoneyOverflow
+	}
+	return q.Int64(), nil
+}
+
+// Major returns the amount in major units, e.g. rands. Only use it for display or approximate calculations.
+func (m Money) Major() float64 {
+	return float64(m.Amount) / math.Pow10(CurrencyDigits(m.Currency))
+}
+
+// IsZero returns true if the amount is zero, regardless of currency.
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// Neg returns the amount with its sign flipped, or ErrMoneyOverflow for the smallest int64, which has no positive.
+func (m Money) Neg() (Money, error) {
+	if m.Amount == math.MinInt64 {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: -m.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) checkCurrency(o Money) error {
+	if m.Currency != o.Currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return nil
+}
+
+// Add returns the sum of m and o, which must have the same currency.
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return Money{}, err
+	}
+	if o.Amount > 0 && m.Amount > math.MaxInt64-o.Amount || o.Amount < 0 && m.Amount < math.MinInt64-o.Amount {
+		return Money{}, ErrMoneyOverflow
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m minus o, which must have the same currency.
+func (m Money) Sub(o Money) (Money, error) {
+	neg, err := o.Neg()
+	if err != nil {
+		return Money{}, err
+	}
+	return m.Add(neg)
+}
+
+// Cmp returns -1, 0 or 1 if m is less than, equal to or more than o, which must have the same currency.
+func (m Money) Cmp(o Money) (int, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return 0, err
+	}
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+// MulRat multiplies the amount by num/den and rounds the result, e.g. MulRat(15, 100, RoundHalfUp) for 15% VAT.
+func (m Money) MulRat(num, den int64, mode RoundingMode) (Money, error) {
+	if den == 0 {
+		return Money{}, errors.New("cannot multiply by a fraction with zero denominator")
+	}
+	r := new(big.Rat).SetFrac(big.NewInt(num), big.NewInt(den))
+	r.Mul(r, new(big.Rat).SetInt64(m.Amount))
+	amount, err := roundRat(r, mode)
+	return Money{Amount: amount, Currency: m.Currency}, err
+}
+
+// Split divides the amount into n parts that add up to the amount exactly, see Allocate.
+func (m Money) Split(n int) ([]Money, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("cannot split into %d parts", n)
+	}
+	ratios := make([]int, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return m.Allocate(ratios...)
+}
+
+// Allocate divides the amount in proportion to the ratios, without losing any minor units.
+// Units left over after rounding down are given one at a time to the first parts,
+// so R100 allocated 1:1:1 gives R33.34, R33.33 and R33.33.
+func (m Money) Allocate(ratios ...int) ([]Money, error) {
+	// The ratios are added as big.Ints, since their sum can overflow
+	total := new(big.Int)
+	for _, r := range ratios {
+		if r < 0 {
+			return nil, fmt.Errorf("cannot allocate with negative ratio %d", r)
+		}
+		total.Add(total, big.NewInt(int64(r)))
+	}
+	if total.Sign() == 0 {
+		return nil, errors.New("cannot allocate with ratios that add up to zero")
+	}
+
+	amount := new(big.Int).SetInt64(m.Amount)
+	abs := new(big.Int).Abs(amount)
+	shares := make([]*big.Int, len(ratios))
+	remainder := new(big.Int).Set(abs)
+	for i, r := range ratios {
+		shares[i] = new(big.Int).Mul(abs, big.NewInt(int64(r)))
+		shares[i].Quo(shares[i], total)
+		remainder.Sub(remainder, shares[i])
+	}
+	one := big.NewInt(1)
+	for i := 0; remainder.Sign() > 0; i++ {
+		if ratios[i] == 0 {
+			continue
+		}
+		shares[i].Add(shares[i], one)
+		remainder.Sub(remainder, one)
+	}
+
+	parts := make([]Money, len(ratios))
+	for i, share := range shares {
+		if amount.Sign() < 0 {
+			share.Neg(share)
+		}
+		// Shares are never larger than the amount, but a wrong result must not wrap silently
+		if !share.IsInt64() {
+			return nil, ErrMoneyOverflow
+		}
+		parts[i] = Money{Amount: share.Int64(), Currency: m.Currency}
+	}
+	return parts, nil
+}
+
+// Decimal formats the absolute amount with the currency's number of decimals, the point as decimal separator,
+// and the group separator every 3 digits, e.g. "1 234,56" for Decimal(",", " ").
+func (m Money) Decimal(point, group string) string {
+	digits := CurrencyDigits(m.Currency)
+	abs := strconv.FormatUint(absInt64(m.Amount), 10)
+	if len(abs) <= digits {
+		abs = strings.Repeat("0", digits-len(abs)+1) + abs
+	}
+	whole, fraction := abs[:len(abs)-digits], abs[len(abs)-digits:]
+
+	var b strings.Builder
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(group)
+		}
+		b.WriteRune(c)
+	}
+	if digits > 0 {
+		b.WriteString(point)
+		b.WriteString(fraction)
+	}
+	return b.String()
+}
+
+func absInt64(i int64) uint64 {
+	if i < 0 {
+		return uint64(-(i + 1)) + 1
+	}
+	return uint64(i)
+}
+
+// String returns the currency code and amount with a decimal point, e.g. "ZAR 1234.56".
+func (m Money) String() string {
+	s := m.Currency + " " + m.Decimal(".", "")
+	if m.Amount < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+type moneyLocale struct {
+	point, group string
+	symbolAfter  bool
+	symbolSpace  bool
+}
+
+var moneyLocales = map[string]moneyLocale{
+	"en-ZA": {point: ",", group: " ", symbolSpace: true},
+	"af-ZA": {point: ",", group: " ", symbolSpace: true},
+	"en-US": {point: ".", group: ","},
+	"en-GB": {point: ".", group: ","},
+	"de-DE": {point: ",", group: ".", symbolAfter: true, symbolSpace: true},
+	"fr-FR": {point: ",", group: " ", symbolAfter: true, symbolSpace: true},
+}
+
+// Format returns the amount with the currency symbol and separators of the locale, e.g. "R 1 234,56" for en-ZA,
+// "$1,234.56" for en-US and "1.234,56 €" for de-DE.
+// Unknown locales use the currency code with a decimal point and comma grouping, e.g. "ZAR 1,234.56".
+func (m Money) Format(locale string) string {
+	l, ok := moneyLocales[locale]
+	symbol := CurrencySymbol(m.Currency)
+	if !ok {
+		l = moneyLocale{point: ".", group: ","}
+		symbol = m.Currency
+	}
+
+	s := JoinSymbol(m.Decimal(l.point, l.group), symbol, l.symbolAfter, l.symbolSpace)
+	if m.Amount < 0 {
+		s = "-" + s
+	}
+	return s
+}
+
+// JoinSymbol puts a currency symbol before a formatted amount, or after it, with a space between them if space is
+// true or the symbol is a currency code, e.g. "R 12,50", "$12.50", "12,50 €" or "KWD 12.345".
+func JoinSymbol(amount, symbol string, after, space bool) string {
+	sep := ""
+	if space || IsCurrencyCode(symbol) {
+		sep = " "
+	}
+	if after {
+		return amount + sep + symbol
+	}
+	return symbol + sep + amount
+}
+
+// UnmarshalJSON reads Money as written by the default JSON encoding, and checks that the currency is a valid code.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Amount   int64  `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if !IsCurrencyCode(v.Currency) {
+		return fmt.Errorf("invalid currency %q", v.Currency)
+	}
+	*m = Money(v)
+	return nil
+}
+
+// Value stores Money as its String, e.g. "ZAR 1234.56".
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads Money written by Value. Other values, like integers, are refused, since their currency is unknown.
+func (m *Money) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		parsed, err := parseMoneyString(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	default:
+		return fmt.Errorf("could not scan %v as money", src)
+	}
+}
+
+// parseMoneyString parses the format written by Money.String.
+func parseMoneyString(s string) (Money, error) {
+	neg := strings.HasPrefix(s, "-")
+	currency, amount, ok := strings.Cut(strings.TrimPrefix(s, "-"), " ")
+	if !ok || !IsCurrencyCode(currency) {
+		return Money{}, fmt.Errorf("could not scan %q as money", s)
+	}
+
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok || strings.ContainsAny(amount, "+-eE/") {
+		return Money{}, fmt.Errorf("could not scan %q as money", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(pow10(CurrencyDigits(currency))))
+	if !r.IsInt() {
+		return Money{}, fmt.Errorf("%q has more decimals than %s allows", s, currency)
+	}
+	if neg {
+		r.Neg(r)
+	}
+	minor, err := roundRat(r, RoundHalfUp)
+	return Money{Amount: minor, Currency: currency}, err
+}
@@ -0,0 +1,148 @@
+package stdext
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestMoneyArithmetic(t *testing.T) {
+	sum, err := ZAR(1050).Add(ZAR(-75))
+	assert.NoError(t, err)
+	assert.Equal(t, ZAR(975), sum)
+
+	diff, err := ZAR(1050).Sub(ZAR(2000))
+	assert.NoError(t, err)
+	assert.Equal(t, ZAR(-950), diff)
+
+	_, err = ZAR(100).Add(NewMoney(100, "USD"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	_, err = ZAR(math.MaxInt64).Add(ZAR(1))
+	assert.ErrorIs(t, err, ErrMoneyOverflow)
+	_, err = ZAR(0).Sub(ZAR(math.MinInt64))
+	assert.ErrorIs(t, err, ErrMoneyOverflow)
+	neg, err := ZAR(-5).Neg()
+	assert.NoError(t, err)
+	assert.Equal(t, ZAR(5), neg)
+	_, err = ZAR(math.MinInt64).Neg()
+	assert.ErrorIs(t, err, ErrMoneyOverflow)
+
+	c, err := ZAR(5).Cmp(ZAR(7))
+	assert.NoError(t, err)
+	assert.Equal(t, -1, c)
+}
+
+func TestMoneyRounding(t *testing.T) {
+	cases := []struct {
+		amount   int64
+		mode     RoundingMode
+		expected int64
+	}{
+		{250, RoundHalfUp, 3},
+		{250, RoundHalfEven, 2},
+		{350, RoundHalfEven, 4},
+		{-250, RoundHalfUp, -3},
+		{-250, RoundHalfEven, -2},
+		{249, RoundHalfUp, 2},
+	}
+	for _, c := range cases {
+		result, err := ZAR(c.amount).MulRat(1, 100, c.mode)
+		assert.NoError(t, err)
+		assert.Equal(t, ZAR(c.expected), result, "%d with mode %d", c.amount, c.mode)
+	}
+
+	vat, err := ZAR(9999).MulRat(15, 100, RoundHalfUp)
+	assert.NoError(t, err)
+	assert.Equal(t, ZAR(1500), vat)
+
+	m, err := MoneyFromFloat(1.005, "ZAR", RoundHalfUp)
+	assert.NoError(t, err)
+	assert.Equal(t, ZAR(101), m)
+	m, err = MoneyFromFloat(1.005, "ZAR", RoundHalfEven)
+	assert.NoError(t, err)
+	assert.Equal(t, ZAR(100), m)
+	m, err = MoneyFromFloat(1234.5, "JPY", RoundHalfUp)
+	assert.NoError(t, err)
+	assert.Equal(t, NewMoney(1235, "JPY"), m)
+	_, err = MoneyFromFloat(math.NaN(), "ZAR", RoundHalfUp)
+	assert.Error(t, err)
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	parts, err := ZAR(10000).Split(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []Money{ZAR(3334), ZAR(3333), ZAR(3333)}, parts)
+
+	parts, err = ZAR(-10000).Split(3)
+	assert.NoError(t, err)
+	assert.Equal(t, []Money{ZAR(-3334), ZAR(-3333), ZAR(-3333)}, parts)
+
+	parts, err = ZAR(5).Allocate(0, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []Money{ZAR(0), ZAR(3), ZAR(2)}, parts)
+
+	parts, err = ZAR(math.MaxInt64).Allocate(70, 30)
+	assert.NoError(t, err)
+	sum, err := parts[0].Add(parts[1])
+	assert.NoError(t, err)
+	assert.Equal(t, ZAR(math.MaxInt64), sum)
+
+	parts, err = ZAR(math.MinInt64).Allocate(math.MaxInt, math.MaxInt, 1)
+	assert.NoError(t, err, "ratios that add up past MaxInt don't wrap")
+	total := ZAR(0)
+	for _, p := range parts {
+		assert.True(t, p.Amount <= 0)
+		total, err = total.Add(p)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, ZAR(math.MinInt64), total)
+
+	_, err = ZAR(5).Split(0)
+	assert.Error(t, err)
+	_, err = ZAR(5).Allocate(1, -1)
+	assert.Error(t, err)
+}
+
+func TestMoneyFormat(t *testing.T) {
+	assert.Equal(t, "ZAR 1234567.89", ZAR(123456789).String())
+	assert.Equal(t, "-ZAR 0.05", ZAR(-5).String())
+	assert.Equal(t, "R 1 234 567,89", ZAR(123456789).Format("en-ZA"))
+	assert.Equal(t, "-R 0,05", ZAR(-5).Format("en-ZA"))
+	assert.Equal(t, "$1,234.56", NewMoney(123456, "USD").Format("en-US"))
+	assert.Equal(t, "1.234,56 €", NewMoney(123456, "EUR").Format("de-DE"))
+	assert.Equal(t, "¥1,234", NewMoney(1234, "JPY").Format("en-US"))
+	assert.Equal(t, "ZAR 1,234.56", ZAR(123456).Format("xx-XX"))
+	assert.Equal(t, "-ZAR 92233720368547758.08", ZAR(math.MinInt64).String())
+}
+
+func TestMoneyEncoding(t *testing.T) {
+	b, err := json.Marshal(ZAR(-1050))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"amount":-1050,"currency":"ZAR"}`, string(b))
+
+	var m Money
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, ZAR(-1050), m)
+	assert.Error(t, json.Unmarshal([]byte(`{"amount":5,"currency":"rand"}`), &m))
+
+	v, err := NewMoney(-123456, "KWD").Value()
+	assert.NoError(t, err)
+	assert.Equal(t, "-KWD 123.456", v)
+	assert.NoError(t, m.Scan([]byte(v.(string))))
+	assert.Equal(t, NewMoney(-123456, "KWD"), m)
+
+	for _, money := range []Money{ZAR(0), ZAR(-5), NewMoney(1234, "JPY"), ZAR(math.MaxInt64), ZAR(math.MinInt64)} {
+		v, err := money.Value()
+		assert.NoError(t, err)
+		var scanned Money
+		assert.NoError(t, scanned.Scan(v))
+		assert.Equal(t, money, scanned, "Scan reads what Value wrote")
+	}
+
+	assert.Error(t, m.Scan(int64(750)), "integers have no currency")
+	assert.Error(t, m.Scan("ZAR 1.005"))
+	assert.Error(t, m.Scan("ZAR 1e3"))
+	assert.Error(t, m.Scan("1.00"))
+	assert.Error(t, m.Scan(1.5))
+}
@@ -0,0 +1,100 @@
+// Package validation checks South African ID numbers, branch codes, references and IBANs.
+// Checks return a *FieldError, or Errors for several fields, which can be sent to clients with
+//
+//	webutil.ClientError(w, err, validation.Info(err), http.StatusBadRequest)
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Codes of FieldErrors, for clients that want to react to specific problems.
+const (
+	CodeRequired    = "required"
+	CodeLength      = "length"
+	CodeFormat      = "format"
+	CodeChecksum    = "checksum"
+	CodeDate        = "date"
+	CodeCitizenship = "citizenship"
+	CodeCountry     = "country"
+)
+
+// FieldError is a problem with a single field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newFieldError(field, code, format string, args ...interface{}) *FieldError {
+	return &FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// InField returns a copy of the error for a different field name, e.g. when a form calls the ID number "idNumber".
+func (e *FieldError) InField(field string) *FieldError {
+	c := *e
+	c.Field = field
+	return &c
+}
+
+// Errors are problems with several fields.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, f := range e {
+		messages[i] = f.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Collect combines the errors of several checks. Nil errors are skipped, and the result is nil if all are nil.
+// Errors that are not validation errors are returned as is, since they are not the client's fault.
+func Collect(errs ...error) error {
+	var result Errors
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var fieldErr *FieldError
+		var fieldErrs Errors
+		switch {
+		case errors.As(err, &fieldErr):
+			result = append(result, fieldErr)
+		case errors.As(err, &fieldErrs):
+			result = append(result, fieldErrs...)
+		default:
+			return err
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// Info returns the fields of a validation error to send with webutil.ClientError, as {"fields": [...]}
+// with the field, code and message of every FieldError. It returns nil for other errors.
+func Info(err error) map[string]interface{} {
+	var fieldErr *FieldError
+	var fieldErrs Errors
+	switch {
+	case errors.As(err, &fieldErr):
+		fieldErrs = Errors{fieldErr}
+	case errors.As(err, &fieldErrs):
+	default:
+		return nil
+	}
+
+	fields := make([]map[string]interface{}, len(fieldErrs))
+	for i, f := range fieldErrs {
+		fields[i] = map[string]interface{}{"field": f.Field, "code": f.Code, "message": f.Message}
+	}
+	return map[string]interface{}{"fields": fields}
+}
@@ -0,0 +1,94 @@
+package validation
+
+import (
+	"github.com/Direct-Debit/go-commons/stdext"
+	"strings"
+)
+
+// ibanLengths are the IBAN lengths of countries that use IBANs.
+var ibanLengths = map[string]int{
+	"AD": 24, "AE": 23, "AT": 20, "AZ": 28, "BA": 20, "BE": 16, "BG": 22, "BH": 22, "BR": 29, "CH": 21,
+	"CR": 22, "CY": 28, "CZ": 24, "DE": 22, "DK": 18, "DO": 28, "EE": 20, "EG": 29, "ES": 24, "FI": 18,
+	"FO": 18, "FR": 27, "GB": 22, "GE": 22, "GI": 23, "GL": 18, "GR": 27, "GT": 28, "HR": 21, "HU": 28,
+	"IE": 22, "IL": 23, "IS": 26, "IT": 27, "JO": 30, "KW": 30, "KZ": 20, "LB": 28, "LI": 21, "LT": 20,
+	"LU": 20, "LV": 21, "MC": 27, "MD": 24, "ME": 22, "MK": 19, "MR": 27, "MT": 31, "MU": 30, "NL": 18,
+	"NO": 15, "PK": 24, "PL": 28, "PS": 29, "PT": 25, "QA": 29, "RO": 24, "RS": 22, "SA": 24, "SE": 24,
+	"SI": 19, "SK": 24, "SM": 27, "TN": 24, "TR": 26, "UA": 29, "VG": 24, "XK": 20,
+}
+
+// IBAN is a valid International Bank Account Number.
+type IBAN struct {
+	Country     string
+	CheckDigits string
+	BBAN        string // The country specific basic bank account number
+}
+
+// ParseIBAN checks an IBAN, which may contain spaces and lower case letters:
+// a known country code, the length for the country, and the mod-97 check digits.
+func ParseIBAN(s string) (IBAN, error) {
+	iban := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	if iban == "" {
+		return IBAN{}, newFieldError(FieldIBAN, CodeRequired, "IBAN is required")
+	}
+	for _, c := range iban {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return IBAN{}, newFieldError(FieldIBAN, CodeFormat, "IBAN may only contain letters and digits")
+		}
+	}
+	if len(iban) < 4 {
+		return IBAN{}, newFieldError(FieldIBAN, CodeLength, "IBAN is too short")
+	}
+
+	length, ok := ibanLengths[iban[:2]]
+	if !ok {
+		return IBAN{}, newFieldError(FieldIBAN, CodeCountry, "%s is not a country that uses IBANs", iban[:2])
+	}
+	if len(iban) != length {
+		return IBAN{}, newFieldError(FieldIBAN, CodeLength, "%s IBANs must be %d characters", iban[:2], length)
+	}
+	if !isDigits(iban[2:4]) {
+		return IBAN{}, newFieldError(FieldIBAN, CodeFormat, "IBAN check digits must be digits")
+	}
+	if ibanMod97(iban) != 1 {
+		return IBAN{}, newFieldError(FieldIBAN, CodeChecksum, "IBAN check digits are wrong")
+	}
+	return IBAN{Country: iban[:2], CheckDigits: iban[2:4], BBAN: iban[4:]}, nil
+}
+
+// ValidateIBAN is ParseIBAN for when only the error is needed.
+func ValidateIBAN(s string) error {
+	_, err := ParseIBAN(s)
+	return err
+}
+
+// ibanMod97 moves the first four characters to the end, replaces letters with 10 to 35, and returns the number mod 97.
+func ibanMod97(iban string) int {
+	rem := 0
+	for _, c := range iban[4:] + iban[:4] {
+		if c >= 'A' && c <= 'Z' {
+			v := int(c-'A') + 10
+			rem = (rem*100 + v) % 97
+		} else {
+			rem = (rem*10 + int(c-'0')) % 97
+		}
+	}
+	return rem
+}
+
+// String returns the IBAN in electronic format, without spaces.
+func (i IBAN) String() string {
+	return i.Country + i.CheckDigits + i.BBAN
+}
+
+// Format returns the IBAN in print format, in groups of four characters.
+func (i IBAN) Format() string {
+	s := i.String()
+	var b strings.Builder
+	for j := 0; j < len(s); j += 4 {
+		if j > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s[j:stdext.Min(j+4, len(s))])
+	}
+	return b.String()
+}
@@ -0,0 +1,110 @@
+package validation
+
+import (
+	"encoding/json"
+	"github.com/Direct-Debit/go-commons/webutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func assertCode(t *testing.T, err error, code string, msgAndArgs ...interface{}) {
+	var fieldErr *FieldError
+	if assert.ErrorAs(t, err, &fieldErr, msgAndArgs...) {
+		assert.Equal(t, code, fieldErr.Code, msgAndArgs...)
+	}
+}
+
+func TestParseIDNumber(t *testing.T) {
+	id, err := ParseIDNumber("8001015009087")
+	assert.NoError(t, err)
+	assert.Equal(t, IDNumber{
+		Number:      "8001015009087",
+		BirthDate:   time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC),
+		Female:      false,
+		Citizenship: CitizenSA,
+	}, id)
+
+	id, err = ParseIDNumber("050101 0009 18 8")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC), id.BirthDate)
+	assert.True(t, id.Female)
+	assert.Equal(t, PermanentResident, id.Citizenship)
+
+	invalid := map[string]string{
+		"":              CodeRequired,
+		"800101500908":  CodeLength,
+		"80010150090A7": CodeFormat,
+		"8013015009087": CodeDate,
+		"8001015009387": CodeCitizenship,
+		"8001015009088": CodeChecksum,
+	}
+	for number, code := range invalid {
+		assertCode(t, ValidateIDNumber(number), code, number)
+	}
+}
+
+func TestBranchCodes(t *testing.T) {
+	assert.NoError(t, ValidateBranchCode("051001"))
+	assertCode(t, ValidateBranchCode("51001"), CodeLength)
+	assertCode(t, ValidateBranchCode("05100A"), CodeFormat)
+	assertCode(t, ValidateBranchCode(" "), CodeRequired)
+
+	bank, ok := UniversalBankFor("470010")
+	assert.True(t, ok)
+	assert.Equal(t, "Capitec", bank)
+	_, ok = UniversalBankFor("123456")
+	assert.False(t, ok)
+}
+
+func TestValidateReference(t *testing.T) {
+	assert.NoError(t, ValidateReference("CONTRACT-123/A", 14))
+	assertCode(t, ValidateReference("CONTRACT-123/AB", 14), CodeLength)
+	assertCode(t, ValidateReference("contract", 14), CodeFormat)
+	assertCode(t, ValidateReference("", 14), CodeRequired)
+}
+
+func TestParseIBAN(t *testing.T) {
+	iban, err := ParseIBAN("gb82 west 1234 5698 7654 32")
+	assert.NoError(t, err)
+	assert.Equal(t, IBAN{Country: "GB", CheckDigits: "82", BBAN: "WEST12345698765432"}, iban)
+	assert.Equal(t, "GB82WEST12345698765432", iban.String())
+	assert.Equal(t, "GB82 WEST 1234 5698 7654 32", iban.Format())
+
+	assert.NoError(t, ValidateIBAN("DE89370400440532013000"))
+	assertCode(t, ValidateIBAN("DE88370400440532013000"), CodeChecksum)
+	assertCode(t, ValidateIBAN("DE8937040044053201300"), CodeLength)
+	assertCode(t, ValidateIBAN("ZA89370400440532013000"), CodeCountry)
+	assertCode(t, ValidateIBAN("DE89-3704"), CodeFormat)
+	assertCode(t, ValidateIBAN(""), CodeRequired)
+}
+
+func TestClientError(t *testing.T) {
+	err := Collect(
+		ValidateIDNumber("8001015009087"),
+		ValidateBranchCode("51001"),
+		ValidateReference("ref", 14),
+	)
+	assert.Len(t, err, 2)
+	assert.Nil(t, Collect(nil, ValidateIBAN("DE89370400440532013000")))
+	assert.Nil(t, Info(assert.AnError))
+	assert.Equal(t, assert.AnError, Collect(ValidateBranchCode(""), assert.AnError))
+
+	var idErr *FieldError
+	assert.ErrorAs(t, ValidateIDNumber(""), &idErr)
+	assert.Equal(t, "idNumber: ID number is required", idErr.InField("idNumber").Error())
+
+	w := httptest.NewRecorder()
+	assert.True(t, webutil.ClientError(w, err, Info(err), http.StatusBadRequest))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "branch_code: branch code must be 6 digits; reference: reference may not contain 'r'", body["error"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"field": "branch_code", "code": "length", "message": "branch code must be 6 digits"},
+		map[string]interface{}{"field": "reference", "code": "format", "message": "reference may not contain 'r'"},
+	}, body["fields"])
+}
@@ -0,0 +1,166 @@
+package validation
+
+import (
+	"strings"
+	"time"
+)
+
+// Default field names of the errors returned by the checks in this package.
+const (
+	FieldIDNumber   = "id_number"
+	FieldBranchCode = "branch_code"
+	FieldReference  = "reference"
+	FieldIBAN       = "iban"
+)
+
+// Citizenship of the holder of an ID number, from its 11th digit.
+const (
+	CitizenSA         = "citizen"
+	PermanentResident = "permanent_resident"
+	Refugee           = "refugee"
+)
+
+// IDNumber is a valid South African ID number.
+type IDNumber struct {
+	Number      string
+	BirthDate   time.Time
+	Female      bool
+	Citizenship string
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// luhnValid checks the Luhn check digit at the end of a string of digits.
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ParseIDNumber checks a South African ID number: 13 digits with a valid date of birth, citizenship digit
+// and Luhn check digit. Birth dates are placed in the most recent century that is not in the future.
+func ParseIDNumber(id string) (IDNumber, error) {
+	id = strings.ReplaceAll(strings.TrimSpace(id), " ", "")
+	switch {
+	case id == "":
+		return IDNumber{}, newFieldError(FieldIDNumber, CodeRequired, "ID number is required")
+	case len(id) != 13:
+		return IDNumber{}, newFieldError(FieldIDNumber, CodeLength, "ID number must be 13 digits")
+	case !isDigits(id):
+		return IDNumber{}, newFieldError(FieldIDNumber, CodeFormat, "ID number must only contain digits")
+	}
+
+	now := time.Now()
+	birth, err := time.Parse("20060102", "20"+id[:6])
+	if err == nil && birth.After(now) {
+		birth, err = time.Parse("20060102", "19"+id[:6])
+	}
+	if err != nil {
+		return IDNumber{}, newFieldError(FieldIDNumber, CodeDate, "ID number does not start with a valid date of birth")
+	}
+
+	result := IDNumber{Number: id, BirthDate: birth, Female: id[6] < '5'}
+	switch id[10] {
+	case '0':
+		result.Citizenship = CitizenSA
+	case '1':
+		result.Citizenship = PermanentResident
+	case '2':
+		result.Citizenship = Refugee
+	default:
+		return IDNumber{}, newFieldError(FieldIDNumber, CodeCitizenship, "ID number has an invalid citizenship digit")
+	}
+
+	if !luhnValid(id) {
+		return IDNumber{}, newFieldError(FieldIDNumber, CodeChecksum, "ID number check digit is wrong")
+	}
+	return result, nil
+}
+
+// ValidateIDNumber is ParseIDNumber for when only the error is needed.
+func ValidateIDNumber(id string) error {
+	_, err := ParseIDNumber(id)
+	return err
+}
+
+// UniversalBranchCodes are the universal branch codes of South African banks, by bank name.
+var UniversalBranchCodes = map[string]string{
+	"ABSA":             "632005",
+	"Access Bank":      "410506",
+	"African Bank":     "430000",
+	"Bank Zero":        "888000",
+	"Bidvest Bank":     "462005",
+	"Capitec":          "470010",
+	"Capitec Business": "450105",
+	"Discovery Bank":   "679000",
+	"FNB":              "250655",
+	"Grindrod Bank":    "584000",
+	"Investec":         "580105",
+	"Nedbank":          "198765",
+	"Postbank":         "460005",
+	"Sasfin Bank":      "683000",
+	"Standard Bank":    "051001",
+	"TymeBank":         "678910",
+}
+
+// ValidateBranchCode checks that a branch code is 6 digits.
+// Use the cdv package to check that it belongs to a bank and matches the account number.
+func ValidateBranchCode(code string) error {
+	code = strings.TrimSpace(code)
+	switch {
+	case code == "":
+		return newFieldError(FieldBranchCode, CodeRequired, "branch code is required")
+	case len(code) != 6:
+		return newFieldError(FieldBranchCode, CodeLength, "branch code must be 6 digits")
+	case !isDigits(code):
+		return newFieldError(FieldBranchCode, CodeFormat, "branch code must only contain digits")
+	}
+	return nil
+}
+
+// UniversalBankFor returns the bank whose universal branch code is code, if it is one.
+func UniversalBankFor(code string) (string, bool) {
+	for bank, c := range UniversalBranchCodes {
+		if c == code {
+			return bank, true
+		}
+	}
+	return "", false
+}
+
+const referenceChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 .,-/&()'+:?"
+
+// ValidateReference checks a payment or mandate reference: not blank, at most maxLength characters,
+// and only upper case letters, digits, spaces and the punctuation banks accept (.,-/&()'+:?).
+func ValidateReference(ref string, maxLength int) error {
+	switch {
+	case strings.TrimSpace(ref) == "":
+		return newFieldError(FieldReference, CodeRequired, "reference is required")
+	case len(ref) > maxLength:
+		return newFieldError(FieldReference, CodeLength, "reference must be at most %d characters", maxLength)
+	}
+	for _, c := range ref {
+		if !strings.ContainsRune(referenceChars, c) {
+			return newFieldError(FieldReference, CodeFormat, "reference may not contain %q", c)
+		}
+	}
+	return nil
+}